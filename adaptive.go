@@ -0,0 +1,54 @@
+package intern
+
+const (
+	// The load factor at which the table grows is held in sixteenths. It starts at 3/4 and
+	// adaptive growth keeps it between 1/2 and 7/8
+	defaultLoadLimit = 12
+	minLoadLimit     = 8
+	maxLoadLimit     = 14
+	// adaptiveWindow is the number of inserts between adjustments of the load limit
+	adaptiveWindow = 1024
+)
+
+// adaptiveGrowth adjusts the load factor at which the table grows based on how long the
+// probe sequences of recent inserts have been. Key sets that cluster badly make the
+// table grow sooner, and ones that spread well let it fill further.
+type adaptiveGrowth struct {
+	// target is the mean number of slots an insert should examine. Zero means adaptive
+	// growth is off
+	target float64
+	// limit is the load factor at which the table grows, in sixteenths. Zero means the
+	// default
+	limit   int
+	probes  int
+	inserts int
+}
+
+// loadLimit returns the load factor at which the table grows, in sixteenths
+func (a *adaptiveGrowth) loadLimit() int {
+	if a.limit == 0 {
+		return defaultLoadLimit
+	}
+	return a.limit
+}
+
+// record notes an insert that examined the given number of slots, and adjusts the load
+// limit at the end of each window
+func (a *adaptiveGrowth) record(probes int) {
+	a.probes += probes
+	a.inserts++
+	if a.inserts < adaptiveWindow {
+		return
+	}
+	mean := float64(a.probes) / float64(a.inserts)
+	a.probes, a.inserts = 0, 0
+
+	limit := a.loadLimit()
+	switch {
+	case mean > a.target && limit > minLoadLimit:
+		limit--
+	case mean < a.target/2 && limit < maxLoadLimit:
+		limit++
+	}
+	a.limit = limit
+}
@@ -0,0 +1,47 @@
+package intern_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveGrowth(t *testing.T) {
+	tests := []struct {
+		name      string
+		target    float64
+		threshold float64
+	}{
+		// Inserts almost always examine more than one slot, so the table grows as early
+		// as it may
+		{"strict", 1, 0.5},
+		// Probe sequences stay well short of 100, so the table fills as far as it may
+		{"relaxed", 100, 0.875},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			in := intern.New(16, intern.WithAdaptiveGrowth(test.target))
+			for j := 0; j < 100000; j++ {
+				in.Save(strconv.Itoa(j))
+			}
+			s := in.Stats()
+			assert.Equal(t, test.threshold, s.GrowthThreshold)
+			assert.True(t, float64(s.Len)/float64(s.Cap) <= test.threshold, s)
+			for j := 0; j < 100000; j++ {
+				offset, ok := in.Lookup(strconv.Itoa(j))
+				assert.True(t, ok)
+				assert.Equal(t, strconv.Itoa(j), in.Get(offset))
+			}
+		})
+	}
+}
+
+func TestAdaptiveGrowthOff(t *testing.T) {
+	in := intern.New(16)
+	for j := 0; j < 10000; j++ {
+		in.Save(strconv.Itoa(j))
+	}
+	assert.Equal(t, 0.75, in.Stats().GrowthThreshold)
+}
@@ -0,0 +1,178 @@
+package intern
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+	"unsafe"
+)
+
+const (
+	// arenaChunkShift sets the size of each block of memory that strings are copied into,
+	// which is 256KB
+	arenaChunkShift = 18
+	// arenaHugeChunkShift sets the chunk size to one huge page when huge pages are used
+	arenaHugeChunkShift = 21
+	// arenaMinChunkShift and arenaMaxChunkShift limit the chunk size set by WithChunkSize
+	arenaMinChunkShift = 8
+	arenaMaxChunkShift = 30
+)
+
+// arena holds the bytes of the stored strings. They are copied one after another into
+// large chunks, each preceded by its length as a varint, so the GC sees a few big byte
+// slices with no pointers in them instead of a great many strings. A string is identified
+// by its offset from the start of the first chunk. Strings are never removed, and offsets
+// are handed out in increasing order.
+//
+// A string too long for one chunk is given a run of chunks cut from a single allocation,
+// so its bytes are contiguous and it is read just like any other string. Whatever is left
+// of the last chunk of the run is used for the strings that follow.
+type arena struct {
+	chunks [][]byte
+	// current is the unused remainder of the last chunk
+	current []byte
+	// shift is log2 of the chunk size. It is set by WithChunkSize, or else when the first
+	// chunk is allocated
+	shift uint
+	// huge asks for chunks to be huge pages
+	huge bool
+	// meta reserves a byte before each string's length for eviction to keep track of how
+	// the string is used
+	meta bool
+	// released is the number of chunks at the start that compaction has emptied and let go
+	released int
+}
+
+// Get returns the string stored at offset
+func (a *arena) Get(offset int) string {
+	return readString(a.chunks, a.shift, offset)
+}
+
+// readString reads the string at offset from chunks of size 1<<shift
+func readString(chunks [][]byte, shift uint, offset int) string {
+	data := chunks[offset>>shift][offset&(1<<shift-1):]
+	l, n := binary.Uvarint(data)
+	if l == 0 {
+		return ""
+	}
+	return unsafe.String(&data[n], int(l))
+}
+
+// Size returns the number of bytes allocated to hold strings, including space not yet
+// used
+func (a *arena) Size() int {
+	return (len(a.chunks) - a.released) << a.shift
+}
+
+// chunkSize returns the size of each chunk
+func (a *arena) chunkSize() int {
+	if a.shift != 0 {
+		return 1 << a.shift
+	}
+	if a.huge {
+		return 1 << arenaHugeChunkShift
+	}
+	return 1 << arenaChunkShift
+}
+
+// space returns the number of bytes needed to store a string of length l
+func (a *arena) space(l int) int {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(l)) + l
+	if a.meta {
+		n++
+	}
+	return n
+}
+
+// metaByte returns the byte reserved before the string at offset. The string starts in
+// the same chunk as its byte
+func (a *arena) metaByte(offset int) *byte {
+	offset--
+	return &a.chunks[offset>>a.shift][offset&(1<<a.shift-1)]
+}
+
+// growth returns the number of bytes the arena would allocate to store a string of
+// length l
+func (a *arena) growth(l int) int {
+	if a.space(l) <= len(a.current) {
+		return 0
+	}
+	return a.chunksFor(l) * a.chunkSize()
+}
+
+// nextOffset returns the offset a string of length l would be stored at
+func (a *arena) nextOffset(l int) int {
+	offset := len(a.chunks) * a.chunkSize()
+	if a.space(l) <= len(a.current) {
+		offset -= len(a.current)
+	}
+	if a.meta {
+		offset++
+	}
+	return offset
+}
+
+// growthPair is growth for storing a string of length l1 and then one of length l2. It
+// also returns the offset the second string would be stored at
+func (a *arena) growthPair(l1, l2 int) (growth, offset int) {
+	size := a.chunkSize()
+	chunks, current := len(a.chunks), len(a.current)
+	for _, l := range [2]int{l1, l2} {
+		if a.space(l) > current {
+			n := a.chunksFor(l)
+			growth += n * size
+			chunks += n
+			current = n * size
+		}
+		offset = chunks*size - current
+		current -= a.space(l)
+	}
+	if a.meta {
+		offset++
+	}
+	return growth, offset
+}
+
+// chunksFor returns the number of new chunks needed to hold a string of length l
+func (a *arena) chunksFor(l int) int {
+	size := a.chunkSize()
+	return (a.space(l) + size - 1) / size
+}
+
+// canGrow returns true if the arena can take the new chunks needed for a string of
+// length l without offsets in them overflowing an int
+func (a *arena) canGrow(l int) bool {
+	size := a.chunkSize()
+	return l < math.MaxInt-size && len(a.chunks)+a.chunksFor(l) <= math.MaxInt/size
+}
+
+// save copies val into the arena and returns its offset
+func (a *arena) save(val string) int {
+	l := a.space(len(val))
+	size := a.chunkSize()
+	if l > len(a.current) {
+		if a.shift == 0 {
+			a.shift = uint(bits.TrailingZeros(uint(size)))
+		}
+		// Long strings get a run of chunks that share one allocation
+		n := a.chunksFor(len(val))
+		b := allocBytes(n*size, a.huge)
+		for j := range n {
+			a.chunks = append(a.chunks, b[j*size:(j+1)*size:(j+1)*size])
+		}
+		a.current = b
+	}
+	offset := len(a.chunks)*size - len(a.current)
+	if a.meta {
+		a.current[0] = 0
+		a.current = a.current[1:]
+		offset++
+		l--
+	}
+	// A long string runs on from current into the other chunks of its run
+	n := binary.PutUvarint(a.current, uint64(len(val)))
+	copy(a.current[n:], val)
+	a.current = a.current[l:]
+	return offset
+}
@@ -0,0 +1,65 @@
+package intern_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArena(t *testing.T) {
+	var in intern.Intern
+
+	// Enough strings to fill several chunks, including empty strings in between
+	vals := make([]string, 0, 30000)
+	for j := 0; j < 10000; j++ {
+		vals = append(vals, strconv.Itoa(j), strings.Repeat("x", j%100+1)+strconv.Itoa(j), "")
+	}
+	offsets := make([]int, len(vals))
+	for j, val := range vals {
+		offsets[j] = in.Save(val)
+	}
+	assert.Equal(t, 20001, in.Len())
+	assert.True(t, in.Size() > 1<<18, in.Size())
+	assert.Zero(t, in.Size()%(1<<18))
+
+	last := -1
+	for j, val := range vals {
+		assert.Equal(t, val, in.Get(offsets[j]))
+		if j < 3 || val != "" {
+			// New strings get increasing offsets
+			assert.True(t, offsets[j] > last, j)
+			last = offsets[j]
+		}
+	}
+}
+
+func TestArenaLongStrings(t *testing.T) {
+	for _, opts := range [][]intern.Option{nil, {intern.WithEviction(intern.EvictLFU)}, {intern.WithCuckoo()}} {
+		in := intern.New(16, opts...)
+		vals := []string{
+			"before",
+			strings.Repeat("a", 1<<18),
+			"between",
+			strings.Repeat("b", 5<<20+3),
+			"after",
+		}
+		offsets := make([]int, len(vals))
+		for j, val := range vals {
+			offsets[j] = in.Save(val)
+		}
+		for j, val := range vals {
+			assert.Equal(t, val, in.Get(offsets[j]))
+			offset, ok := in.Lookup(val)
+			assert.True(t, ok)
+			assert.Equal(t, offsets[j], offset)
+		}
+		assert.NoError(t, in.CheckInvariants())
+
+		// Each long string takes just enough whole chunks, and the strings that follow
+		// use up what is left of the last one
+		assert.Equal(t, (1+2+21)<<18, in.Size())
+	}
+}
@@ -0,0 +1,65 @@
+package intern
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrAvro is returned when Avro data doesn't hold the string or enum expected
+var ErrAvro = errors.New("intern: invalid Avro data")
+
+// ReadAvroString decodes the Avro string or bytes value at the start of b, interns it
+// and returns it with the rest of b. An Avro string is its length as a zig-zag varint
+// followed by its bytes. Use it from hand-written or generated decoders for the string
+// fields of records read from Kafka and the like, where the same values arrive again and
+// again. For libraries that decode into maps, such as goavro, pass the result to
+// DeduplicateValue instead.
+func (i *Intern) ReadAvroString(b []byte) (val string, rest []byte, err error) {
+	l, n := binary.Varint(b)
+	switch {
+	case n <= 0:
+		return "", b, fmt.Errorf("%w: bad string length", ErrAvro)
+	case l < 0:
+		return "", b, fmt.Errorf("%w: negative string length %d", ErrAvro, l)
+	case l > int64(len(b)-n):
+		return "", b, fmt.Errorf("%w: data is truncated", ErrAvro)
+	}
+	return i.DeduplicateBytes(b[n : n+int(l)]), b[n+int(l):], nil
+}
+
+// AppendAvroString appends val to dst as an Avro string
+func AppendAvroString(dst []byte, val string) []byte {
+	dst = binary.AppendVarint(dst, int64(len(val)))
+	return append(dst, val...)
+}
+
+// AvroEnum decodes the values of an Avro enum. Avro sends an enum as the index of its
+// symbol in the schema, so every value decoded is one of the symbols, which are
+// interned once when the AvroEnum is made.
+type AvroEnum struct {
+	symbols []string
+}
+
+// AvroEnum returns a decoder for an enum with the given symbols, in schema order
+func (i *Intern) AvroEnum(symbols []string) *AvroEnum {
+	return &AvroEnum{symbols: i.DeduplicateAll(symbols)}
+}
+
+// Read decodes the enum value at the start of b and returns its symbol with the rest
+// of b
+func (e *AvroEnum) Read(b []byte) (symbol string, rest []byte, err error) {
+	k, n := binary.Varint(b)
+	switch {
+	case n <= 0:
+		return "", b, fmt.Errorf("%w: bad enum index", ErrAvro)
+	case k < 0 || k >= int64(len(e.symbols)):
+		return "", b, fmt.Errorf("%w: enum index %d out of range for %d symbols", ErrAvro, k, len(e.symbols))
+	}
+	return e.symbols[k], b[n:], nil
+}
+
+// Symbols returns the interned symbols, in schema order
+func (e *AvroEnum) Symbols() []string {
+	return e.symbols
+}
@@ -0,0 +1,74 @@
+package intern_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAvroString(t *testing.T) {
+	in := intern.New(16)
+	vals := []string{"", "hat", strings.Repeat("a", 200)}
+	var b []byte
+	for _, val := range vals {
+		b = intern.AppendAvroString(b, val)
+	}
+	assert.Equal(t, []byte{0, 6, 'h', 'a', 't', 0x90, 0x03}, b[:7])
+
+	rest := b
+	for _, want := range vals {
+		var val string
+		var err error
+		val, rest, err = in.ReadAvroString(rest)
+		assert.NoError(t, err)
+		assert.Equal(t, want, val)
+		assert.Equal(t, unsafe.StringData(in.Deduplicate(want)), unsafe.StringData(val))
+	}
+	assert.Empty(t, rest)
+}
+
+func TestAvroStringErrors(t *testing.T) {
+	in := intern.New(16)
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{name: "empty", data: nil, err: "intern: invalid Avro data: bad string length"},
+		{name: "negative", data: []byte{1}, err: "intern: invalid Avro data: negative string length -1"},
+		{name: "short", data: []byte{6, 'h'}, err: "intern: invalid Avro data: data is truncated"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, rest, err := in.ReadAvroString(test.data)
+			assert.EqualError(t, err, test.err)
+			assert.True(t, errors.Is(err, intern.ErrAvro))
+			assert.Equal(t, test.data, rest)
+		})
+	}
+}
+
+func TestAvroEnum(t *testing.T) {
+	in := intern.New(16)
+	symbols := []string{"HEARTS", "SPADES", "CLUBS"}
+	e := in.AvroEnum(symbols)
+	assert.Equal(t, symbols, e.Symbols())
+
+	symbol, rest, err := e.Read([]byte{2, 4})
+	assert.NoError(t, err)
+	assert.Equal(t, "SPADES", symbol)
+	assert.Equal(t, unsafe.StringData(in.Deduplicate("SPADES")), unsafe.StringData(symbol))
+	symbol, rest, err = e.Read(rest)
+	assert.NoError(t, err)
+	assert.Equal(t, "CLUBS", symbol)
+	assert.Empty(t, rest)
+
+	_, _, err = e.Read([]byte{6})
+	assert.EqualError(t, err, "intern: invalid Avro data: enum index 3 out of range for 3 symbols")
+	_, _, err = e.Read(nil)
+	assert.EqualError(t, err, "intern: invalid Avro data: bad enum index")
+}
@@ -0,0 +1,71 @@
+package intern
+
+import "math/bits"
+
+const (
+	// bloomBitsPerEntry is the size of the Bloom filter per entry the table can hold
+	bloomBitsPerEntry = 10
+	// bloomProbes is the number of bits set for each entry. With 10 bits per entry this
+	// gives a false positive rate of a little over 1%
+	bloomProbes = 4
+)
+
+// bloomFilter is a Bloom filter over the hashes of the strings in the table. If it
+// says a hash is absent we can skip probing the table. It is built from the hashes
+// already stored, so never needs to look at the strings themselves.
+type bloomFilter struct {
+	bits []uint64
+	mask uint64
+}
+
+// reset clears the filter and sizes it to hold entries strings
+func (f *bloomFilter) reset(entries int) {
+	n := max(entries*bloomBitsPerEntry, 64)
+	n = 1 << bits.Len(uint(n-1))
+	f.bits = make([]uint64, n/64)
+	f.mask = uint64(n - 1)
+}
+
+// add records hash in the filter
+func (f *bloomFilter) add(hash uint64) {
+	h1, h2 := hash, hash>>32|1
+	for j := 0; j < bloomProbes; j++ {
+		bit := h1 & f.mask
+		f.bits[bit/64] |= 1 << (bit % 64)
+		h1 += h2
+	}
+}
+
+// mayContain returns false if hash has definitely not been added to the filter
+func (f *bloomFilter) mayContain(hash uint64) bool {
+	h1, h2 := hash, hash>>32|1
+	for j := 0; j < bloomProbes; j++ {
+		bit := h1 & f.mask
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+		h1 += h2
+	}
+	return true
+}
+
+// bytes returns the memory used by the filter
+func (f *bloomFilter) bytes() int {
+	if f == nil {
+		return 0
+	}
+	return len(f.bits) * 8
+}
+
+// rebuildFilter sizes the filter for the current table and fills it with the hashes of
+// every stored string
+func (i *Intern) rebuildFilter() {
+	i.filter.reset(i.maxEntries())
+	for _, t := range []table{i.table, i.oldTable, i.cuckoo.table()} {
+		for _, s := range t.slots {
+			if s.index != 0 {
+				i.filter.add(i.spread(s.hash))
+			}
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package intern_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilter(t *testing.T) {
+	in := intern.New(16, intern.WithBloomFilter(), intern.WithProbeStats(1))
+
+	offsets := make(map[string]int)
+	for j := 0; j < 10000; j++ {
+		val := strconv.Itoa(j)
+		offsets[val] = in.Save(val)
+	}
+
+	probes := func() (total uint64) {
+		for _, count := range in.Stats().ProbeLengths {
+			total += count
+		}
+		return total
+	}
+	before := probes()
+
+	for j := 10000; j < 20000; j++ {
+		_, ok := in.Lookup(strconv.Itoa(j))
+		assert.False(t, ok)
+	}
+	// Only the filter's false positives should reach the table
+	assert.True(t, probes()-before < 500, probes()-before)
+
+	for val, offset := range offsets {
+		found, ok := in.Lookup(val)
+		assert.True(t, ok, val)
+		assert.Equal(t, offset, found)
+	}
+
+	in.Rehash()
+	for val, offset := range offsets {
+		found, ok := in.Lookup(val)
+		assert.True(t, ok, val)
+		assert.Equal(t, offset, found)
+	}
+	assert.NotZero(t, in.MemoryUsage().Filter)
+}
+
+func TestLookup(t *testing.T) {
+	var in intern.Intern
+	_, ok := in.Lookup("hat")
+	assert.False(t, ok)
+
+	hat := in.Save("hat")
+	offset, ok := in.Lookup("hat")
+	assert.True(t, ok)
+	assert.Equal(t, hat, offset)
+
+	_, ok = in.Lookup("sat")
+	assert.False(t, ok)
+	assert.Equal(t, 1, in.Len())
+}
+
+func BenchmarkLookupMiss(b *testing.B) {
+	for _, test := range []struct {
+		name string
+		opts []intern.Option
+	}{
+		{"plain", nil},
+		{"bloom", []intern.Option{intern.WithBloomFilter()}},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			in := intern.New(16, test.opts...)
+			for j := 0; j < 1000000; j++ {
+				in.Save(strconv.Itoa(j))
+			}
+			misses := make([]string, 1000)
+			for j := range misses {
+				misses[j] = "miss" + strconv.Itoa(j)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for j := 0; j < b.N; j++ {
+				in.Lookup(misses[j%len(misses)])
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package intern_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCache(t *testing.T) {
+	s := intern.NewShared(16)
+	hat := s.Deduplicate("hat")
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := s.NewCache(64)
+			assert.Equal(t, datapointer(hat), datapointer(c.Deduplicate("hat")))
+			assert.Equal(t, datapointer(hat), datapointer(c.Deduplicate("hat")))
+
+			vals := make([]string, 100)
+			for round := 0; round < 10; round++ {
+				for j := range vals {
+					vals[j] = strconv.Itoa(j % 37)
+				}
+				c.DeduplicateInPlace(vals)
+				for j, val := range vals {
+					assert.Equal(t, strconv.Itoa(j%37), val)
+					assert.Equal(t, datapointer(s.Deduplicate(val)), datapointer(val))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 38, s.Len())
+}
+
+func BenchmarkCache(b *testing.B) {
+	vals := make([]string, 1000)
+	for j := range vals {
+		vals[j] = "value" + strconv.Itoa(j%100)
+	}
+
+	b.Run("shared", func(b *testing.B) {
+		s := intern.NewShared(16)
+		b.RunParallel(func(pb *testing.PB) {
+			for j := 0; pb.Next(); j++ {
+				s.Deduplicate(vals[j%len(vals)])
+			}
+		})
+	})
+	b.Run("cache", func(b *testing.B) {
+		s := intern.NewShared(16)
+		b.RunParallel(func(pb *testing.PB) {
+			c := s.NewCache(256)
+			for j := 0; pb.Next(); j++ {
+				c.Deduplicate(vals[j%len(vals)])
+			}
+		})
+	})
+}
@@ -0,0 +1,331 @@
+package intern
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// ErrCBOR is returned when CBOR data doesn't hold the string expected
+var ErrCBOR = errors.New("intern: invalid CBOR string")
+
+const (
+	// CBOR major types
+	cborBytes = 2
+	cborText  = 3
+	cborTag   = 6
+
+	// cborTagNamespace marks an item inside which strings may be referenced, and
+	// cborTagRef is a reference to an earlier string in the namespace. Both are defined
+	// by the stringref extension, http://cbor.schmorp.de/stringref
+	cborTagNamespace = 256
+	cborTagRef       = 25
+)
+
+// cborRefMinLen returns how long a string must be to be numbered when n strings have
+// been numbered already. A shorter string takes no more space in full than a reference
+// to it would.
+func cborRefMinLen(n int) int {
+	switch {
+	case n < 24:
+		return 3
+	case n < 1<<8:
+		return 4
+	case n < 1<<16:
+		return 5
+	case uint64(n) < 1<<32:
+		return 7
+	default:
+		return 11
+	}
+}
+
+// CBOREncoder writes CBOR text and byte strings using the stringref extension. Inside a
+// namespace the first copy of a string long enough to gain is written in full and
+// numbered, and each later copy is written as a reference to that number, so a message
+// that repeats its keys and values carries each of them only once. Text and byte strings
+// are numbered together, but a text string is never referred to by a byte string with
+// the same bytes, or the other way round. The strings of each type are kept in a Dict
+// over an Intern of the encoder's own.
+//
+// A namespace may hold another, opened with AppendNestedNamespace. The strings of the
+// inner namespace are numbered on their own, and once it is closed with
+// EndNestedNamespace the enclosing namespace's numbering carries on where it left off.
+type CBOREncoder struct {
+	refs *cborNamespace
+	// outer holds the numbering of each enclosing namespace, innermost last
+	outer []*cborNamespace
+}
+
+// cborNamespace holds the strings numbered in a namespace. text and bytes give the
+// strings of each type an ID of their own, and textRefs and bytesRefs map each ID to the
+// string's number in the namespace.
+type cborNamespace struct {
+	text, bytes         *Dict
+	textRefs, bytesRefs []uint64
+}
+
+// NewCBOREncoder returns an encoder ready for its first namespace
+func NewCBOREncoder() *CBOREncoder {
+	e := &CBOREncoder{}
+	e.reset()
+	return e
+}
+
+func (e *CBOREncoder) reset() {
+	e.refs = &cborNamespace{text: New(16).Dict(), bytes: New(16).Dict()}
+}
+
+// AppendNamespace appends the tag that opens a stringref namespace, which the caller
+// follows with exactly one item, usually an array or map. It forgets every string
+// numbered so far, as the strings of a new namespace are numbered from zero, so use
+// AppendNestedNamespace for a namespace inside another.
+func (e *CBOREncoder) AppendNamespace(dst []byte) []byte {
+	clear(e.outer)
+	e.outer = e.outer[:0]
+	e.reset()
+	return appendCBORHead(dst, cborTag, cborTagNamespace)
+}
+
+// AppendNestedNamespace is like AppendNamespace, but opens the namespace inside the
+// current one, whose numbering is put aside until EndNestedNamespace
+func (e *CBOREncoder) AppendNestedNamespace(dst []byte) []byte {
+	e.outer = append(e.outer, e.refs)
+	e.reset()
+	return appendCBORHead(dst, cborTag, cborTagNamespace)
+}
+
+// EndNestedNamespace closes the namespace opened by the last call to
+// AppendNestedNamespace, once its item has been written, and goes back to the strings
+// numbered in the enclosing namespace. It does nothing if no nested namespace is open.
+func (e *CBOREncoder) EndNestedNamespace() {
+	if len(e.outer) == 0 {
+		return
+	}
+	e.refs = e.outer[len(e.outer)-1]
+	e.outer[len(e.outer)-1] = nil
+	e.outer = e.outer[:len(e.outer)-1]
+}
+
+// AppendString appends val as a CBOR text string, or as a reference if it has been
+// written already in this namespace
+func (e *CBOREncoder) AppendString(dst []byte, val string) []byte {
+	return e.append(dst, cborText, val, e.refs.text, &e.refs.textRefs)
+}
+
+// AppendBytes appends val as a CBOR byte string, or as a reference if it has been
+// written already in this namespace
+func (e *CBOREncoder) AppendBytes(dst []byte, val []byte) []byte {
+	// The Dict copies val if it is numbered, so there is no need to convert it first
+	return e.append(dst, cborBytes, unsafe.String(unsafe.SliceData(val), len(val)), e.refs.bytes, &e.refs.bytesRefs)
+}
+
+// append appends val as a string of the given major type, using d and refs to number
+// the strings of that type
+func (e *CBOREncoder) append(dst []byte, major byte, val string, d *Dict, refs *[]uint64) []byte {
+	if id, ok := d.LookupID(val); ok {
+		dst = appendCBORHead(dst, cborTag, cborTagRef)
+		return appendCBORHead(dst, 0, (*refs)[id])
+	}
+	n := len(e.refs.textRefs) + len(e.refs.bytesRefs)
+	if len(val) >= cborRefMinLen(n) {
+		d.ToID(val)
+		*refs = append(*refs, uint64(n))
+	}
+	dst = appendCBORHead(dst, major, uint64(len(val)))
+	return append(dst, val...)
+}
+
+// CBORDecoder reads CBOR strings written with the stringref extension, interning text
+// strings as it goes. Strings are numbered as they are read just as the encoder
+// numbered them, so the decoder must see every string in the namespace, byte strings
+// included, in order. Nested namespaces are read with ReadNestedNamespace and
+// EndNestedNamespace, at the points where the encoder opened and closed them.
+type CBORDecoder struct {
+	in *Intern
+	// refs holds the numbered strings of the current namespace. Byte strings are kept
+	// as well, so that the numbering stays in step, but are not interned.
+	refs []cborRef
+	// outer holds the numbered strings of each enclosing namespace, innermost last
+	outer [][]cborRef
+}
+
+type cborRef struct {
+	val   string
+	bytes bool
+}
+
+// NewCBORDecoder returns a decoder that interns text strings in in
+func (i *Intern) NewCBORDecoder() *CBORDecoder {
+	return &CBORDecoder{in: i}
+}
+
+// ReadNamespace reads the tag that opens a stringref namespace from the start of b and
+// returns the rest of b, which holds the namespace's item. It forgets the strings of
+// any earlier namespace, so use ReadNestedNamespace for a namespace inside another.
+func (d *CBORDecoder) ReadNamespace(b []byte) (rest []byte, err error) {
+	rest, err = readCBORNamespace(b)
+	if err != nil {
+		return b, err
+	}
+	clear(d.outer)
+	d.outer = d.outer[:0]
+	d.refs = d.refs[:0]
+	return rest, nil
+}
+
+// ReadNestedNamespace is like ReadNamespace, but reads a namespace inside the current
+// one, whose strings are put aside until EndNestedNamespace
+func (d *CBORDecoder) ReadNestedNamespace(b []byte) (rest []byte, err error) {
+	rest, err = readCBORNamespace(b)
+	if err != nil {
+		return b, err
+	}
+	d.outer = append(d.outer, d.refs)
+	d.refs = nil
+	return rest, nil
+}
+
+// EndNestedNamespace closes the namespace opened by the last call to
+// ReadNestedNamespace, once its item has been read, and goes back to the strings
+// numbered in the enclosing namespace. It does nothing if no nested namespace is open.
+func (d *CBORDecoder) EndNestedNamespace() {
+	if len(d.outer) == 0 {
+		return
+	}
+	d.refs = d.outer[len(d.outer)-1]
+	d.outer[len(d.outer)-1] = nil
+	d.outer = d.outer[:len(d.outer)-1]
+}
+
+// readCBORNamespace reads the tag that opens a stringref namespace from the start of b
+// and returns the rest of b
+func readCBORNamespace(b []byte) (rest []byte, err error) {
+	major, v, n, err := cborHead(b)
+	if err != nil {
+		return b, err
+	}
+	if major != cborTag || v != cborTagNamespace {
+		return b, fmt.Errorf("%w: expected a stringref namespace, found major type %d value %d", ErrCBOR, major, v)
+	}
+	return b[n:], nil
+}
+
+// ReadString reads the CBOR text string or string reference at the start of b and
+// returns it, interned, along with the rest of b
+func (d *CBORDecoder) ReadString(b []byte) (val string, rest []byte, err error) {
+	raw, ref, rest, err := d.read(b, cborText)
+	if err != nil {
+		return "", b, err
+	}
+	if ref != nil {
+		return ref.val, rest, nil
+	}
+	val = d.in.DeduplicateBytes(raw)
+	d.number(val, false)
+	return val, rest, nil
+}
+
+// ReadBytes reads the CBOR byte string or string reference at the start of b and
+// returns it along with the rest of b. The bytes returned are part of b, except for a
+// reference, which returns a copy of the string referred to.
+func (d *CBORDecoder) ReadBytes(b []byte) (val []byte, rest []byte, err error) {
+	raw, ref, rest, err := d.read(b, cborBytes)
+	if err != nil {
+		return nil, b, err
+	}
+	if ref != nil {
+		return []byte(ref.val), rest, nil
+	}
+	d.number(string(raw), true)
+	return raw, rest, nil
+}
+
+// number adds val to the numbered strings if it is long enough
+func (d *CBORDecoder) number(val string, bytes bool) {
+	if len(val) >= cborRefMinLen(len(d.refs)) {
+		d.refs = append(d.refs, cborRef{val: val, bytes: bytes})
+	}
+}
+
+// read reads a string of the given major type, or a reference to one, from the start
+// of b. It returns either the string's bytes or the string referred to.
+func (d *CBORDecoder) read(b []byte, want byte) (raw []byte, ref *cborRef, rest []byte, err error) {
+	major, v, n, err := cborHead(b)
+	if err != nil {
+		return nil, nil, b, err
+	}
+	if major == cborTag && v == cborTagRef {
+		major, v, m, err := cborHead(b[n:])
+		if err != nil {
+			return nil, nil, b, err
+		}
+		if major != 0 {
+			return nil, nil, b, fmt.Errorf("%w: string reference is major type %d, not an unsigned integer", ErrCBOR, major)
+		}
+		if v >= uint64(len(d.refs)) {
+			return nil, nil, b, fmt.Errorf("%w: reference to string %d, but only %d are numbered", ErrCBOR, v, len(d.refs))
+		}
+		ref = &d.refs[v]
+		if ref.bytes != (want == cborBytes) {
+			return nil, nil, b, fmt.Errorf("%w: reference to string %d of the wrong type", ErrCBOR, v)
+		}
+		return nil, ref, b[n+m:], nil
+	}
+	if major != want {
+		return nil, nil, b, fmt.Errorf("%w: expected major type %d, found %d", ErrCBOR, want, major)
+	}
+	if v > uint64(len(b)-n) {
+		return nil, nil, b, fmt.Errorf("%w: data is truncated", ErrCBOR)
+	}
+	return b[n : n+int(v)], nil, b[n+int(v):], nil
+}
+
+// cborHead decodes the head of the CBOR item at the start of b, returning its major
+// type, its argument and the length of the head. Indefinite lengths are not supported.
+func cborHead(b []byte) (major byte, v uint64, n int, err error) {
+	if len(b) == 0 {
+		return 0, 0, 0, fmt.Errorf("%w: data is truncated", ErrCBOR)
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), 1, nil
+	case info > 27:
+		return 0, 0, 0, fmt.Errorf("%w: unsupported additional information %d", ErrCBOR, info)
+	}
+	n = 1 + 1<<(info-24)
+	if len(b) < n {
+		return 0, 0, 0, fmt.Errorf("%w: data is truncated", ErrCBOR)
+	}
+	switch info {
+	case 24:
+		v = uint64(b[1])
+	case 25:
+		v = uint64(binary.BigEndian.Uint16(b[1:]))
+	case 26:
+		v = uint64(binary.BigEndian.Uint32(b[1:]))
+	case 27:
+		v = binary.BigEndian.Uint64(b[1:])
+	}
+	return major, v, n, nil
+}
+
+// appendCBORHead appends the head of a CBOR item with the given major type and
+// argument, using the shortest encoding
+func appendCBORHead(dst []byte, major byte, v uint64) []byte {
+	major <<= 5
+	switch {
+	case v < 24:
+		return append(dst, major|byte(v))
+	case v < 1<<8:
+		return append(dst, major|24, byte(v))
+	case v < 1<<16:
+		return binary.BigEndian.AppendUint16(append(dst, major|25), uint16(v))
+	case v < 1<<32:
+		return binary.BigEndian.AppendUint32(append(dst, major|26), uint32(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(dst, major|27), v)
+	}
+}
@@ -0,0 +1,201 @@
+package intern_test
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+	"unsafe"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+// cborExample is the example from the stringref specification
+var cborExample = []string{
+	"1", "222", "333", "4", "555", "666", "777", "888", "999", "aaa", "bbb", "ccc", "ddd",
+	"eee", "fff", "ggg", "hhh", "iii", "jjj", "kkk", "lll", "mmm", "nnn", "ooo", "ppp",
+	"qqq", "rrr", "333", "ssss", "qqq", "rrr", "ssss",
+}
+
+func TestCBOR(t *testing.T) {
+	e := intern.NewCBOREncoder()
+	b := e.AppendNamespace(nil)
+	b = append(b, 0x80|24, byte(len(cborExample)))
+	for _, val := range cborExample {
+		b = e.AppendString(b, val)
+	}
+	// "333" the second time is a reference to string 1. Once 24 strings are numbered
+	// "rrr" is too short to be worth a reference, but "ssss" becomes string 24
+	assert.Equal(t, "d9010098206131633232326333333361346335353563363636", hex.EncodeToString(b[:25]))
+	assert.Equal(t, "d819016473737373d8191763727272d8191818", hex.EncodeToString(b[len(b)-19:]))
+
+	in := intern.New(16)
+	d := in.NewCBORDecoder()
+	rest, err := d.ReadNamespace(b)
+	assert.NoError(t, err)
+	rest = rest[2:]
+	for _, want := range cborExample {
+		var val string
+		val, rest, err = d.ReadString(rest)
+		assert.NoError(t, err)
+		assert.Equal(t, want, val)
+		assert.Equal(t, unsafe.StringData(in.Deduplicate(want)), unsafe.StringData(val))
+	}
+	assert.Empty(t, rest)
+
+	// A new namespace numbers strings from zero again
+	b = e.AppendNamespace(b[:0])
+	b = e.AppendString(b, "333")
+	b = e.AppendString(b, "333")
+	assert.Equal(t, "d9010063333333d81900", hex.EncodeToString(b))
+}
+
+func TestCBORNested(t *testing.T) {
+	// ["aaa", 256(["bbb", "aaa", "bbb"]), "aaa", "ccc"]
+	e := intern.NewCBOREncoder()
+	b := e.AppendNamespace(nil)
+	b = append(b, 0x84)
+	b = e.AppendString(b, "aaa")
+	b = e.AppendNestedNamespace(b)
+	b = append(b, 0x83)
+	b = e.AppendString(b, "bbb")
+	b = e.AppendString(b, "aaa")
+	b = e.AppendString(b, "bbb")
+	e.EndNestedNamespace()
+	b = e.AppendString(b, "aaa")
+	b = e.AppendString(b, "ccc")
+	// Inside the nested namespace "bbb" is string 0 and "aaa" is written in full again.
+	// Outside it "aaa" refers to string 0 of the outer namespace
+	assert.Equal(t, "d901008463616161d90100836362626263616161d81900d8190063636363", hex.EncodeToString(b))
+
+	in := intern.New(16)
+	d := in.NewCBORDecoder()
+	rest, err := d.ReadNamespace(b)
+	assert.NoError(t, err)
+	rest = rest[1:]
+	var got []string
+	var val string
+	val, rest, err = d.ReadString(rest)
+	assert.NoError(t, err)
+	got = append(got, val)
+	rest, err = d.ReadNestedNamespace(rest)
+	assert.NoError(t, err)
+	rest = rest[1:]
+	for range 3 {
+		val, rest, err = d.ReadString(rest)
+		assert.NoError(t, err)
+		got = append(got, val)
+	}
+	d.EndNestedNamespace()
+	for range 2 {
+		val, rest, err = d.ReadString(rest)
+		assert.NoError(t, err)
+		got = append(got, val)
+	}
+	assert.Empty(t, rest)
+	assert.Equal(t, []string{"aaa", "bbb", "aaa", "bbb", "aaa", "ccc"}, got)
+}
+
+func TestCBORBytes(t *testing.T) {
+	// A byte string is numbered along with the text strings
+	b, _ := hex.DecodeString("d901004361626363646566d81900d81901")
+	d := intern.New(16).NewCBORDecoder()
+	rest, err := d.ReadNamespace(b)
+	assert.NoError(t, err)
+
+	raw, rest, err := d.ReadBytes(rest)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("abc"), raw)
+	val, rest, err := d.ReadString(rest)
+	assert.NoError(t, err)
+	assert.Equal(t, "def", val)
+	raw, rest, err = d.ReadBytes(rest)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("abc"), raw)
+	val, rest, err = d.ReadString(rest)
+	assert.NoError(t, err)
+	assert.Equal(t, "def", val)
+	assert.Empty(t, rest)
+}
+
+func TestCBORBytesRoundTrip(t *testing.T) {
+	// Text and byte strings share the numbering, but "abc" as text and as bytes are
+	// different strings
+	type item struct {
+		val   string
+		bytes bool
+	}
+	items := []item{
+		{"abc", false}, {"abc", true}, {"defg", true}, {"abc", false}, {"abc", true},
+		{"hijk", false}, {"defg", true}, {"hijk", false}, {"x", true}, {"x", true},
+	}
+	e := intern.NewCBOREncoder()
+	b := e.AppendNamespace(nil)
+	for _, it := range items {
+		if it.bytes {
+			b = e.AppendBytes(b, []byte(it.val))
+		} else {
+			b = e.AppendString(b, it.val)
+		}
+	}
+	assert.Equal(t, "d90100"+"63616263"+"43616263"+"4464656667"+"d81900"+"d81901"+
+		"6468696a6b"+"d81902"+"d81903"+"4178"+"4178", hex.EncodeToString(b))
+
+	d := intern.New(16).NewCBORDecoder()
+	rest, err := d.ReadNamespace(b)
+	assert.NoError(t, err)
+	for _, it := range items {
+		if it.bytes {
+			var raw []byte
+			raw, rest, err = d.ReadBytes(rest)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte(it.val), raw)
+		} else {
+			var val string
+			val, rest, err = d.ReadString(rest)
+			assert.NoError(t, err)
+			assert.Equal(t, it.val, val)
+		}
+	}
+	assert.Empty(t, rest)
+}
+
+func TestCBORErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "empty", data: "", err: "intern: invalid CBOR string: data is truncated"},
+		{name: "short", data: "63616", err: "intern: invalid CBOR string: data is truncated"},
+		{name: "short head", data: "79", err: "intern: invalid CBOR string: data is truncated"},
+		{name: "indefinite", data: "7f", err: "intern: invalid CBOR string: unsupported additional information 31"},
+		{name: "int", data: "07", err: "intern: invalid CBOR string: expected major type 3, found 0"},
+		{name: "bytes", data: "4161", err: "intern: invalid CBOR string: expected major type 3, found 2"},
+		{name: "unknown ref", data: "d81900", err: "intern: invalid CBOR string: reference to string 0, but only 0 are numbered"},
+		{name: "bad ref", data: "d81960", err: "intern: invalid CBOR string: string reference is major type 3, not an unsigned integer"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, _ := hex.DecodeString(test.data)
+			d := intern.New(16).NewCBORDecoder()
+			_, rest, err := d.ReadString(b)
+			assert.EqualError(t, err, test.err)
+			assert.True(t, errors.Is(err, intern.ErrCBOR))
+			assert.Equal(t, b, rest)
+		})
+	}
+
+	d := intern.New(16).NewCBORDecoder()
+	_, err := d.ReadNamespace([]byte{0x80})
+	assert.EqualError(t, err, "intern: invalid CBOR string: expected a stringref namespace, found major type 4 value 0")
+
+	// A byte string can't be read back as text
+	b, _ := hex.DecodeString("d9010043616263d81900")
+	rest, err := d.ReadNamespace(b)
+	assert.NoError(t, err)
+	_, rest, err = d.ReadBytes(rest)
+	assert.NoError(t, err)
+	_, _, err = d.ReadString(rest)
+	assert.EqualError(t, err, "intern: invalid CBOR string: reference to string 0 of the wrong type")
+}
@@ -0,0 +1,139 @@
+package intern
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrCorrupt is returned by CheckInvariants when the interner's internal state is
+// inconsistent
+var ErrCorrupt = errors.New("intern: internal state is corrupt")
+
+// CheckInvariants examines every entry in the hash table and reports the first
+// inconsistency it finds. Each entry must refer to a string that lies within the arena,
+// its stored hash must match the string, and looking the string up must find that same
+// entry. The number of entries must match Len, no string may be stored twice, and every
+// pinned string must still be present. It takes time in proportion to the number of
+// strings, so suits tests and occasional checks in production rather than every
+// operation. A nil result means nothing was found to be wrong.
+func (i *Intern) CheckInvariants() error {
+	var seen Set
+	entries := 0
+	check := func(where string, pos int, s slot) error {
+		offset := s.index - 1
+		if err := i.checkSlot(s); err != nil {
+			return fmt.Errorf("%w: %s slot %d: %w", ErrCorrupt, where, pos, err)
+		}
+		if seen.Has(offset) {
+			return fmt.Errorf("%w: %s slot %d: offset %d is in the table more than once", ErrCorrupt, where, pos, offset)
+		}
+		seen.Add(offset)
+		entries++
+		return nil
+	}
+
+	if i.cuckoo != nil {
+		for pos, s := range i.cuckoo.slots {
+			if s.index == 0 {
+				continue
+			}
+			if err := check("cuckoo table", pos, s); err != nil {
+				return err
+			}
+			if b1, b2 := i.cuckooBuckets(s.hash); pos/cuckooBucketSize != b1 && pos/cuckooBucketSize != b2 {
+				return fmt.Errorf("%w: cuckoo table slot %d: entry is in bucket %d, not %d or %d", ErrCorrupt, pos, pos/cuckooBucketSize, b1, b2)
+			}
+			if index := i.cuckooFind(i.Get(s.index-1), s.hash); index != s.index {
+				return fmt.Errorf("%w: cuckoo table slot %d: looking up %s finds offset %d", ErrCorrupt, pos, dumpString(i.Get(s.index-1)), index-1)
+			}
+		}
+	} else {
+		for pos, s := range i.table.slots {
+			if s.index == 0 {
+				continue
+			}
+			if err := check("table", pos, s); err != nil {
+				return err
+			}
+			if _, index := i.findInTable(i.table, i.Get(s.index-1), s.hash); index != s.index {
+				return fmt.Errorf("%w: table slot %d: looking up %s finds offset %d", ErrCorrupt, pos, dumpString(i.Get(s.index-1)), index-1)
+			}
+		}
+		// Slots of the old table before the cursor have already been copied or
+		// compacted into the new one
+		for pos := i.oldTableCursor; pos < i.oldTable.len(); pos++ {
+			s := i.oldTable.slots[pos]
+			if s.index == 0 {
+				continue
+			}
+			if err := check("old table", pos, s); err != nil {
+				return err
+			}
+			if _, index := i.findInTable(i.oldTable, i.Get(s.index-1), s.hash); index != s.index {
+				return fmt.Errorf("%w: old table slot %d: looking up %s finds offset %d", ErrCorrupt, pos, dumpString(i.Get(s.index-1)), index-1)
+			}
+		}
+	}
+
+	if entries != i.count {
+		return fmt.Errorf("%w: the table holds %d entries, but the count is %d", ErrCorrupt, entries, i.count)
+	}
+	if i.filter != nil {
+		for offset := range seen.All() {
+			if !i.filter.mayContain(i.spread(i.hash(i.Get(offset)))) {
+				return fmt.Errorf("%w: Bloom filter is missing offset %d", ErrCorrupt, offset)
+			}
+		}
+	}
+	for offset := range i.pinned.All() {
+		if !seen.Has(offset) {
+			return fmt.Errorf("%w: pinned offset %d is not in the table", ErrCorrupt, offset)
+		}
+	}
+	if i.tuples != nil {
+		if err := i.tuples.CheckInvariants(); err != nil {
+			return fmt.Errorf("tuples: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkSlot checks that s refers to a string that matches its hash
+func (i *Intern) checkSlot(s slot) error {
+	offset := s.index - 1
+	if err := i.checkOffset(offset); err != nil {
+		return err
+	}
+	val := i.Get(offset)
+	if h := i.hash(val); h != s.hash {
+		return fmt.Errorf("hash %#x stored for %s, which hashes to %#x", s.hash, dumpString(val), h)
+	}
+	return nil
+}
+
+// checkOffset checks that a whole string is stored at offset
+func (i *Intern) checkOffset(offset int) error {
+	a := &i.arena
+	if len(a.chunks) == 0 {
+		return fmt.Errorf("offset %d, but no strings are stored", offset)
+	}
+	size := 1 << a.shift
+	end := len(a.chunks)*size - len(a.current)
+	if offset < a.released*size || offset >= end {
+		return fmt.Errorf("offset %d is outside the strings stored, from %d to %d", offset, a.released*size, end)
+	}
+	if a.meta && offset&(size-1) == 0 {
+		return fmt.Errorf("offset %d leaves no room for the string's metadata", offset)
+	}
+	chunk := a.chunks[offset>>a.shift]
+	if chunk == nil {
+		return fmt.Errorf("offset %d is in a chunk that has been released", offset)
+	}
+	// Long strings run on past the end of their first chunk
+	l, n := binary.Uvarint(chunk[offset&(size-1):])
+	if n <= 0 || uint64(end-offset-n) < l {
+		return fmt.Errorf("offset %d holds a bad length", offset)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package intern_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckInvariants(t *testing.T) {
+	configs := [][]intern.Option{
+		nil,
+		{intern.WithCuckoo()},
+		{intern.WithBloomFilter()},
+		{intern.WithEviction(intern.EvictLFU)},
+	}
+	for _, opts := range configs {
+		in := intern.New(16, opts...)
+		assert.NoError(t, in.CheckInvariants())
+		// Checking after every save covers the states part way through each resize
+		for j := range 1000 {
+			in.Save("value-" + strconv.Itoa(j))
+			if !assert.NoError(t, in.CheckInvariants(), "after %d", j) {
+				break
+			}
+		}
+		in.Pin(in.Save("value-7"))
+
+		var live intern.Set
+		for j := 0; j < 1000; j += 3 {
+			offset, _ := in.Lookup("value-" + strconv.Itoa(j))
+			live.Add(offset)
+		}
+		in.Collect(live.All())
+		assert.NoError(t, in.CheckInvariants())
+	}
+}
+
+func TestCheckInvariantsCompaction(t *testing.T) {
+	in := intern.New(16)
+	var live intern.Set
+	for j := range 5000 {
+		offset := in.Save(compactVal(j))
+		if j%7 == 0 {
+			live.Add(offset)
+		}
+	}
+	in.StartCompaction(live.All(), nil)
+	for !in.CompactStep(500) {
+		assert.NoError(t, in.CheckInvariants())
+		in.Save(compactVal(10000 + in.Len()))
+	}
+	assert.NoError(t, in.CheckInvariants())
+}
+
+func TestCheckInvariantsCorrupt(t *testing.T) {
+	in := intern.New(16)
+	for j := range 100 {
+		in.Save("a longer value " + strconv.Itoa(j))
+	}
+	offset, _ := in.Lookup("a longer value 42")
+	// GetBytes must never be modified, which makes it a handy way to damage the table
+	in.GetBytes(offset)[0] = 'A'
+
+	err := in.CheckInvariants()
+	assert.True(t, errors.Is(err, intern.ErrCorrupt))
+	assert.Contains(t, err.Error(), `"A longer value 42", which hashes to`)
+}
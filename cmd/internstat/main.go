@@ -0,0 +1,224 @@
+// Command internstat inspects a dictionary file written by WriteTo, WriteSnapshot,
+// WriteCompressed or WriteSnapshotFile. It checks the file thoroughly, then reports
+// how many strings it holds, how the space is divided up, a histogram of string lengths
+// and the longest strings.
+//
+//	internstat [-top n] [-crc32c hex] file...
+//
+// The check covers every offset and, for snapshots, every slot of the lookup table: each
+// string must be found at its own position, so duplicate strings are reported too.
+// Compressed files are checked against their gzip CRC-32. The format itself carries no
+// checksum, so the CRC-32C (Castagnoli) of each file is printed, and -crc32c checks a
+// single file against a CRC-32C recorded when it was written. internstat exits with
+// status 1 if any file fails the check.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/bits"
+	"os"
+	"slices"
+	"strconv"
+
+	"github.com/philpearl/intern"
+)
+
+// Header fields, as documented in the intern package
+const (
+	headerSize = 32
+	flagTable  = 0x1
+	flagGzip   = 0x2
+	slotSize   = 8
+)
+
+// maxShown is how much of each of the longest strings is printed
+const maxShown = 60
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "internstat:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("internstat", flag.ContinueOnError)
+	top := fs.Int("top", 10, "number of longest strings to list")
+	sum := fs.String("crc32c", "", "expected CRC-32C of the file, in hex")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: internstat [-top n] [-crc32c hex] file...")
+	}
+	want := int64(-1)
+	if *sum != "" {
+		if fs.NArg() != 1 {
+			return errors.New("-crc32c checks a single file")
+		}
+		v, err := strconv.ParseUint(*sum, 16, 32)
+		if err != nil {
+			return fmt.Errorf("bad -crc32c value %q", *sum)
+		}
+		want = int64(v)
+	}
+	var failed error
+	for j, name := range fs.Args() {
+		if j > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := inspect(w, name, *top, want); err != nil {
+			fmt.Fprintf(w, "%s: FAILED: %v\n", name, err)
+			failed = errors.New("some files failed the check")
+		}
+	}
+	return failed
+}
+
+// inspect checks one file and writes its report. If want isn't -1 the file's CRC-32C
+// must match it.
+func inspect(w io.Writer, name string, top int, want int64) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	sum := crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli))
+	if want != -1 && int64(sum) != want {
+		return fmt.Errorf("CRC-32C is %08x, expected %08x", sum, want)
+	}
+	if len(data) < headerSize {
+		return fmt.Errorf("%w: data is truncated", intern.ErrFormat)
+	}
+	flags := binary.LittleEndian.Uint32(data[8:])
+	slots := binary.LittleEndian.Uint32(data[12:])
+	count := binary.LittleEndian.Uint64(data[16:])
+	total := binary.LittleEndian.Uint64(data[24:])
+
+	// strings visits every string in order, once the file has passed its check
+	var strings func(yield func(int, string) bool)
+	if flags&flagGzip != 0 {
+		// Compressed data can't be checked in place, so load it. The count in the header
+		// hasn't been checked yet, so the capacity it suggests is limited by the size of
+		// the file
+		in := intern.New(int(min(count, uint64(len(data)))))
+		n, err := in.ReadFrom(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if n != int64(len(data)) {
+			return fmt.Errorf("%d unexpected bytes after the data", int64(len(data))-n)
+		}
+		if uint64(in.Len()) != count {
+			return fmt.Errorf("%d of the %d strings are duplicates", count-uint64(in.Len()), count)
+		}
+		strings = func(yield func(int, string) bool) {
+			k := 0
+			for _, val := range in.AllInOrder() {
+				if !yield(k, val) {
+					return
+				}
+				k++
+			}
+		}
+	} else {
+		f, err := intern.FromBytes(data)
+		if err != nil {
+			return err
+		}
+		for k, val := range f.All() {
+			if found, ok := f.Lookup(val); !ok {
+				return fmt.Errorf("string %d %s is missing from the lookup table", k, quote(val))
+			} else if found != k {
+				return fmt.Errorf("string %d %s duplicates string %d", k, quote(val), found)
+			}
+		}
+		strings = f.All()
+	}
+
+	var hist [65]int
+	type entry struct {
+		k   int
+		val string
+	}
+	var longest []entry
+	for k, val := range strings {
+		hist[bits.Len(uint(len(val)))]++
+		longest = append(longest, entry{k, val})
+	}
+	slices.SortStableFunc(longest, func(a, b entry) int {
+		return len(b.val) - len(a.val)
+	})
+	longest = longest[:min(len(longest), max(top, 0))]
+
+	fmt.Fprintf(w, "%s: OK\n", name)
+	fmt.Fprintf(w, "format:   %s\n", describe(flags))
+	fmt.Fprintf(w, "crc32c:   %08x\n", sum)
+	fmt.Fprintf(w, "strings:  %d\n", count)
+	fmt.Fprintf(w, "size:     %d bytes\n", len(data))
+	if flags&flagGzip == 0 {
+		fmt.Fprintf(w, "  header  %d\n", headerSize)
+		if flags&flagTable != 0 {
+			fmt.Fprintf(w, "  table   %d (%d slots)\n", uint64(slots)*slotSize, slots)
+		}
+		fmt.Fprintf(w, "  offsets %d\n", 8*(count+1))
+		fmt.Fprintf(w, "  strings %d\n", total)
+	} else {
+		fmt.Fprintf(w, "  strings %d before compression\n", total)
+	}
+	if count != 0 {
+		fmt.Fprintf(w, "mean length: %.1f bytes\n", float64(total)/float64(count))
+	}
+
+	fmt.Fprintf(w, "\nlengths:\n")
+	for b, n := range hist {
+		if n == 0 {
+			continue
+		}
+		lo, hi := 0, 0
+		if b > 0 {
+			lo, hi = 1<<(b-1), 1<<b-1
+		}
+		fmt.Fprintf(w, "  %8s %d\n", lengthRange(lo, hi), n)
+	}
+
+	if len(longest) != 0 {
+		fmt.Fprintf(w, "\nlongest strings:\n")
+		for _, e := range longest {
+			fmt.Fprintf(w, "  %d: %d bytes %s\n", e.k, len(e.val), quote(e.val))
+		}
+	}
+	return nil
+}
+
+// describe names the features the flags turn on
+func describe(flags uint32) string {
+	switch {
+	case flags&flagGzip != 0:
+		return "compressed"
+	case flags&flagTable != 0:
+		return "snapshot with lookup table"
+	}
+	return "plain"
+}
+
+// lengthRange labels a histogram bucket
+func lengthRange(lo, hi int) string {
+	if lo == hi {
+		return strconv.Itoa(lo)
+	}
+	return strconv.Itoa(lo) + "-" + strconv.Itoa(hi)
+}
+
+// quote quotes val, shortening it if it is long
+func quote(val string) string {
+	if len(val) > maxShown {
+		return strconv.Quote(val[:maxShown]) + "..."
+	}
+	return strconv.Quote(val)
+}
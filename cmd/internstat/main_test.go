@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, name string, write func(in *intern.Intern, w *bytes.Buffer) error) string {
+	in := intern.New(16)
+	for _, val := range []string{"hat", "scarf", "glove", "a very long string indeed", ""} {
+		in.Save(val)
+	}
+	var b bytes.Buffer
+	assert.NoError(t, write(in, &b))
+	name = filepath.Join(t.TempDir(), name)
+	assert.NoError(t, os.WriteFile(name, b.Bytes(), 0o644))
+	return name
+}
+
+func TestRun(t *testing.T) {
+	writers := map[string]func(in *intern.Intern, w *bytes.Buffer) error{
+		"plain": func(in *intern.Intern, w *bytes.Buffer) error {
+			_, err := in.WriteTo(w)
+			return err
+		},
+		"snapshot with lookup table": func(in *intern.Intern, w *bytes.Buffer) error {
+			_, err := in.WriteSnapshot(w)
+			return err
+		},
+		"compressed": func(in *intern.Intern, w *bytes.Buffer) error {
+			_, err := in.WriteCompressed(w, gzip.BestCompression)
+			return err
+		},
+	}
+	for format, write := range writers {
+		t.Run(format, func(t *testing.T) {
+			name := writeFile(t, "dict.istr", write)
+			var out strings.Builder
+			assert.NoError(t, run([]string{"-top", "2", name}, &out))
+			report := out.String()
+			assert.Contains(t, report, name+": OK\n")
+			assert.Contains(t, report, "format:   "+format+"\n")
+			assert.Contains(t, report, "strings:  5\n")
+			assert.Contains(t, report, "         0 1\n")
+			assert.Contains(t, report, "       2-3 1\n")
+			assert.Contains(t, report, "       4-7 2\n")
+			assert.Contains(t, report, "     16-31 1\n")
+			assert.Contains(t, report, "longest strings:\n  3: 25 bytes \"a very long string indeed\"\n  1: 5 bytes \"scarf\"\n")
+		})
+	}
+}
+
+func TestRunCorrupt(t *testing.T) {
+	name := writeFile(t, "dict.istr", func(in *intern.Intern, w *bytes.Buffer) error {
+		_, err := in.WriteCompressed(w, gzip.BestCompression)
+		return err
+	})
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	// Damage the gzip CRC-32
+	data[len(data)-5] ^= 0xff
+	assert.NoError(t, os.WriteFile(name, data, 0o644))
+
+	var out strings.Builder
+	assert.EqualError(t, run([]string{name}, &out), "some files failed the check")
+	assert.Equal(t, name+": FAILED: intern: invalid dictionary format: gzip: invalid checksum\n", out.String())
+}
+
+func TestRunDuplicates(t *testing.T) {
+	// Rename the second string to match the first, keeping the lengths the same
+	name := writeFile(t, "dict.istr", func(in *intern.Intern, w *bytes.Buffer) error {
+		_, err := in.WriteSnapshot(w)
+		return err
+	})
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	data = bytes.Replace(data, []byte("scarfglove"), []byte("scarfscarf"), 1)
+	assert.NoError(t, os.WriteFile(name, data, 0o644))
+
+	var out strings.Builder
+	assert.Error(t, run([]string{name}, &out))
+	assert.Equal(t, name+": FAILED: string 2 \"scarf\" duplicates string 1\n", out.String())
+}
+
+func TestRunUsage(t *testing.T) {
+	assert.EqualError(t, run(nil, &strings.Builder{}), "usage: internstat [-top n] [-crc32c hex] file...")
+	assert.EqualError(t, run([]string{"-crc32c", "1", "a", "b"}, &strings.Builder{}), "-crc32c checks a single file")
+	assert.EqualError(t, run([]string{"-crc32c", "xyz", "a"}, &strings.Builder{}), `bad -crc32c value "xyz"`)
+}
+
+func TestRunCRC32C(t *testing.T) {
+	name := writeFile(t, "dict.istr", func(in *intern.Intern, w *bytes.Buffer) error {
+		_, err := in.WriteSnapshot(w)
+		return err
+	})
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	sum := fmt.Sprintf("%08x", crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+
+	var out strings.Builder
+	assert.NoError(t, run([]string{"-crc32c", sum, name}, &out))
+	assert.Contains(t, out.String(), name+": OK\n")
+	assert.Contains(t, out.String(), "crc32c:   "+sum+"\n")
+
+	out.Reset()
+	assert.EqualError(t, run([]string{"-crc32c", "12345678", name}, &out), "some files failed the check")
+	assert.Equal(t, name+": FAILED: CRC-32C is "+sum+", expected 12345678\n", out.String())
+}
+
+func TestRunFilledTable(t *testing.T) {
+	// Point every slot of the lookup table at the first string. Looking up a missing
+	// string would then never find an empty slot
+	name := writeFile(t, "dict.istr", func(in *intern.Intern, w *bytes.Buffer) error {
+		_, err := in.WriteSnapshot(w)
+		return err
+	})
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	for pos := range binary.LittleEndian.Uint32(data[12:]) {
+		binary.LittleEndian.PutUint32(data[headerSize+slotSize*pos+4:], 1)
+	}
+	assert.NoError(t, os.WriteFile(name, data, 0o644))
+
+	var out strings.Builder
+	assert.Error(t, run([]string{name}, &out))
+	assert.Equal(t, name+": FAILED: intern: invalid dictionary format: lookup table has no empty slots\n", out.String())
+}
+
+func TestRunHugeLength(t *testing.T) {
+	// A header claiming one string of 256GB, with the offsets to match, is reported as
+	// corrupt rather than loaded
+	for _, flags := range []uint32{0, flagGzip} {
+		data := []byte("ISTR")
+		data = binary.LittleEndian.AppendUint32(data, 1)
+		data = binary.LittleEndian.AppendUint32(data, flags)
+		data = binary.LittleEndian.AppendUint32(data, 0)
+		data = binary.LittleEndian.AppendUint64(data, 1)
+		data = binary.LittleEndian.AppendUint64(data, 1<<38)
+		body := binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, 0), 1<<38)
+		if flags&flagGzip != 0 {
+			b := bytes.NewBuffer(data)
+			w := gzip.NewWriter(b)
+			w.Write(body)
+			w.Close()
+			data = b.Bytes()
+		} else {
+			data = append(data, body...)
+		}
+		name := filepath.Join(t.TempDir(), "dict.istr")
+		assert.NoError(t, os.WriteFile(name, data, 0o644))
+
+		var out strings.Builder
+		assert.Error(t, run([]string{name}, &out))
+		assert.True(t, strings.HasPrefix(out.String(), name+": FAILED: intern: invalid dictionary format: "), out.String())
+	}
+}
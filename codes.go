@@ -0,0 +1,164 @@
+package intern
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// InvalidCode is never the code of a string, as there can be no more than
+// math.MaxUint32 codes. Use it rather than 0 to mean "no string" where codes are kept.
+const InvalidCode uint32 = math.MaxUint32
+
+// Codes is like Dict, but numbers the strings with uint32 codes and keeps the offset of
+// each in an []int32. Codes run from 0 without gaps, so they are the smallest integers
+// that can stand for the strings, which suits bitmaps and the codes of columnar
+// formats far better than sparse arena offsets. The price is that offsets are limited
+// to 2GB, and there can be no more than 4 billion codes. Once either limit is reached
+// new strings can't be stored: Save panics and TrySave returns ErrTooLarge.
+//
+// Like a Dict, the Codes number the strings that are left afresh after Collect or
+// Evict, in the order of their old codes. While an Intern has Codes a compaction is
+// done in one go, as Collect does, so that the offsets stay small.
+type Codes struct {
+	in *Intern
+	// offsets maps each code to the string's offset in the Intern, and codes the other
+	// way
+	offsets []int32
+	codes   map[int]uint32
+}
+
+// Codes returns the view of i that gives every string a code, making it on the first
+// call. Strings already stored are numbered in offset order, as AllInOrder gives them,
+// and strings stored afterwards, whether through the Codes or directly, get the next
+// codes in turn. Every call returns the same Codes. Any compaction in progress is
+// completed first.
+// Codes panics with ErrTooLarge if a string is already stored at an offset that doesn't
+// fit in an int32.
+func (i *Intern) Codes() *Codes {
+	if i.codes == nil {
+		if i.compact != nil {
+			i.finishMigration()
+		}
+		c := &Codes{in: i, codes: make(map[int]uint32, i.count)}
+		for offset := range i.AllInOrder() {
+			if !c.canAdd(offset) {
+				panic(fmt.Errorf("%w: offset %d doesn't fit in a code's int32", ErrTooLarge, offset))
+			}
+			c.add(offset)
+		}
+		i.codes = c
+	}
+	return i.codes
+}
+
+// Code returns the code of val, storing it if it is new. Like Save, it panics if val is
+// new and can't be stored.
+func (c *Codes) Code(val string) uint32 {
+	return c.codes[c.in.Save(val)]
+}
+
+// LookupCode returns the code of val and true if it is present, without storing it, or
+// InvalidCode and false if not
+func (c *Codes) LookupCode(val string) (uint32, bool) {
+	offset, ok := c.in.Lookup(val)
+	if !ok {
+		return InvalidCode, false
+	}
+	code, ok := c.codes[offset]
+	if !ok {
+		return InvalidCode, false
+	}
+	return code, true
+}
+
+// FromCode returns the string with the given code
+func (c *Codes) FromCode(code uint32) string {
+	return c.in.Get(int(c.offsets[code]))
+}
+
+// Len returns the number of codes given out
+func (c *Codes) Len() int {
+	return len(c.offsets)
+}
+
+// Offsets returns the code to offset array, indexed by code. It is shared with c, so
+// must not be modified.
+func (c *Codes) Offsets() []int32 {
+	return c.offsets
+}
+
+// canAdd returns true if a string stored at offset can be given a code. checkBudget
+// calls it before a string is stored, so that add never fails
+func (c *Codes) canAdd(offset int) bool {
+	return offset <= math.MaxInt32 && uint64(len(c.offsets)) < math.MaxUint32
+}
+
+// add gives the string at offset the next code
+func (c *Codes) add(offset int) {
+	c.codes[offset] = uint32(len(c.offsets))
+	c.offsets = append(c.offsets, int32(offset))
+}
+
+// remap numbers the strings afresh from 0, in the order of their old codes, once
+// rebuild has moved them as described by remap
+func (c *Codes) remap(remap map[int]int) {
+	codes := make(map[int]uint32, len(remap))
+	offsets := c.offsets[:0]
+	for _, offset := range c.offsets {
+		if offset, ok := remap[int(offset)]; ok {
+			codes[offset] = uint32(len(offsets))
+			offsets = append(offsets, int32(offset))
+		}
+	}
+	c.offsets, c.codes = offsets, codes
+}
+
+// SortOrder returns every code, ordered by the strings they stand for, as compared by
+// CompareOffsets. Inverting it gives each code's rank, so that a dictionary-encoded
+// column can be sorted by comparing the ranks of its codes instead of the strings.
+func (c *Codes) SortOrder() []int {
+	order := make([]int, len(c.offsets))
+	for code := range order {
+		order[code] = code
+	}
+	slices.SortFunc(order, func(a, b int) int {
+		return c.in.CompareOffsets(int(c.offsets[a]), int(c.offsets[b]))
+	})
+	return order
+}
+
+// EncodeColumn returns the code of each string in vals, storing any that are new, so
+// that a column of strings can be written as its codes alongside a dictionary read
+// from FromCode or Offsets. Like Code, it panics if a string can't be stored.
+func (c *Codes) EncodeColumn(vals []string) (codes []uint32) {
+	codes = make([]uint32, 0, len(vals))
+	for len(vals) > 0 {
+		n := c.in.makeRoom(len(vals))
+		for _, val := range vals[:n] {
+			offset, err := c.in.save(val)
+			if err != nil {
+				panic(err)
+			}
+			codes = append(codes, c.codes[offset])
+		}
+		vals = vals[n:]
+	}
+	return codes
+}
+
+// DecodeColumn returns the string for each code, reversing EncodeColumn. The strings
+// are not copies: each is backed by the Intern's own memory, so equal strings share it.
+func (c *Codes) DecodeColumn(codes []uint32) []string {
+	return c.AppendDecodeColumn(make([]string, 0, len(codes)), codes)
+}
+
+// AppendDecodeColumn is like DecodeColumn, but appends the strings to dst and returns
+// the extended slice. Pass a reused slice as dst to decode a column in batches without
+// allocating.
+func (c *Codes) AppendDecodeColumn(dst []string, codes []uint32) []string {
+	for _, code := range codes {
+		dst = append(dst, c.FromCode(code))
+	}
+	return dst
+}
@@ -0,0 +1,183 @@
+package intern_test
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodes(t *testing.T) {
+	in := intern.New(16)
+	in.Save("zero")
+	in.Save("one")
+
+	c := in.Codes()
+	assert.Equal(t, 2, c.Len())
+	assert.Equal(t, uint32(0), c.Code("zero"))
+	assert.Equal(t, uint32(1), c.Code("one"))
+	assert.Equal(t, uint32(2), c.Code("two"))
+
+	// Strings saved directly get codes too
+	offset := in.Save("three")
+	code, ok := c.LookupCode("three")
+	assert.True(t, ok)
+	assert.Equal(t, uint32(3), code)
+	assert.Equal(t, int32(offset), c.Offsets()[3])
+	code, ok = c.LookupCode("four")
+	assert.False(t, ok)
+	assert.Equal(t, intern.InvalidCode, code)
+
+	for j := 4; j < 1000; j++ {
+		assert.Equal(t, uint32(j), c.Code(strconv.Itoa(j)))
+	}
+	assert.Equal(t, 1000, c.Len())
+	assert.Len(t, c.Offsets(), 1000)
+	for j := uint32(0); j < 1000; j++ {
+		assert.Equal(t, j, c.Code(c.FromCode(j)))
+	}
+	assert.Equal(t, "two", c.FromCode(2))
+}
+
+func TestCodesColumn(t *testing.T) {
+	c := intern.New(16).Codes()
+	assert.Equal(t, uint32(0), c.Code("a"))
+
+	col := make([]string, 1000)
+	for j := range col {
+		col[j] = strconv.Itoa(j % 100)
+	}
+	col[10] = "a"
+	codes := c.EncodeColumn(col)
+	assert.Len(t, codes, len(col))
+	assert.Equal(t, []uint32{1, 2, 3}, codes[:3])
+	assert.Equal(t, uint32(0), codes[10])
+	assert.Equal(t, codes[1], codes[101])
+	assert.Equal(t, 101, c.Len())
+	assert.Equal(t, col, c.DecodeColumn(codes))
+	assert.Empty(t, c.DecodeColumn(nil))
+}
+
+func TestCodesDecodeShared(t *testing.T) {
+	in := intern.New(16)
+	c := in.Codes()
+	codes := c.EncodeColumn([]string{"hat", "cat", "hat", "cat"})
+
+	vals := c.DecodeColumn(codes)
+	assert.Equal(t, datapointer(in.Get(in.Save("hat"))), datapointer(vals[0]))
+	assert.Equal(t, datapointer(vals[0]), datapointer(vals[2]))
+	assert.Equal(t, datapointer(vals[1]), datapointer(vals[3]))
+
+	buf := make([]string, 0, len(codes))
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = c.AppendDecodeColumn(buf[:0], codes)
+	})
+	assert.Equal(t, 0.0, allocs)
+	assert.Equal(t, []string{"hat", "cat", "hat", "cat"}, buf)
+	assert.Equal(t, []string{"x", "hat", "cat", "hat", "cat"}, c.AppendDecodeColumn([]string{"x"}, codes))
+}
+
+func TestCodesSortOrder(t *testing.T) {
+	in := intern.New(16)
+	c := in.Codes()
+	codes := c.EncodeColumn([]string{"pear", "apple", "fig", "apple", "banana"})
+	order := c.SortOrder()
+	assert.Equal(t, []int{1, 3, 2, 0}, order)
+
+	// Sort the column by ranks, without looking at the strings
+	rank := make([]int, len(order))
+	for r, code := range order {
+		rank[code] = r
+	}
+	slices.SortFunc(codes, func(a, b uint32) int { return rank[a] - rank[b] })
+	assert.Equal(t, []string{"apple", "apple", "banana", "fig", "pear"}, c.DecodeColumn(codes))
+
+	assert.Equal(t, -1, in.CompareOffsets(in.Save("apple"), in.Save("fig")))
+	assert.Equal(t, 0, in.CompareOffsets(in.Save("fig"), in.Save("fig")))
+	assert.Equal(t, 1, in.CompareOffsets(in.Save("pear"), in.Save("fig")))
+}
+
+func TestCodesSame(t *testing.T) {
+	in := intern.New(16)
+	assert.True(t, in.Codes() == in.Codes())
+}
+
+func TestCodesCollect(t *testing.T) {
+	in := intern.New(16)
+	c := in.Codes()
+	for j := 0; j < 100; j++ {
+		c.Code(strconv.Itoa(j))
+	}
+	var live []int
+	for j := 0; j < 100; j += 3 {
+		live = append(live, in.Save(strconv.Itoa(j)))
+	}
+	in.Collect(slices.Values(live))
+
+	// The strings left are numbered afresh in their old order
+	assert.Equal(t, 34, c.Len())
+	for code := uint32(0); code < uint32(c.Len()); code++ {
+		assert.Equal(t, strconv.Itoa(int(code)*3), c.FromCode(code))
+		assert.Equal(t, code, c.Code(c.FromCode(code)))
+	}
+	_, ok := c.LookupCode("1")
+	assert.False(t, ok)
+	assert.Equal(t, uint32(34), c.Code("new"))
+}
+
+func TestCodesCompaction(t *testing.T) {
+	in := intern.New(16)
+	c := in.Codes()
+	for j := 0; j < 100; j++ {
+		c.Code(strconv.Itoa(j))
+	}
+	var live []int
+	for j := 0; j < 100; j += 3 {
+		live = append(live, in.Save(strconv.Itoa(j)))
+	}
+	moved := map[int]int{}
+	in.StartCompaction(slices.Values(live), func(old, new int) { moved[old] = new })
+	for !in.CompactStep(16) {
+	}
+
+	// With Codes the compaction is done at once, and the offsets start again from 0
+	assert.Len(t, moved, 34)
+	assert.Equal(t, 34, c.Len())
+	for code, offset := range c.Offsets() {
+		assert.Equal(t, strconv.Itoa(code*3), in.Get(int(offset)))
+		assert.Equal(t, moved[live[code]], int(offset))
+	}
+	assert.Equal(t, uint32(34), c.Code("new"))
+}
+
+func TestCodesAfterCompactionStarted(t *testing.T) {
+	in := intern.New(16)
+	var live []int
+	for j := 0; j < 100; j++ {
+		offset := in.Save(strconv.Itoa(j))
+		if j%3 == 0 {
+			live = append(live, offset)
+		}
+	}
+	in.StartCompaction(slices.Values(live), nil)
+	in.CompactStep(4)
+
+	// The compaction is finished before the strings are numbered
+	c := in.Codes()
+	assert.Equal(t, 34, c.Len())
+	var got []string
+	for code, offset := range c.Offsets() {
+		val := in.Get(int(offset))
+		got = append(got, val)
+		lcode, ok := c.LookupCode(val)
+		assert.True(t, ok)
+		assert.Equal(t, uint32(code), lcode)
+	}
+	var want []string
+	for j := 0; j < 100; j += 3 {
+		want = append(want, strconv.Itoa(j))
+	}
+	assert.ElementsMatch(t, want, got)
+}
@@ -0,0 +1,20 @@
+package intern
+
+// Collator orders strings for a locale. *collate.Collator from golang.org/x/text/collate
+// implements it, so a collator made with collate.New(language.German) can be passed to
+// WithCollator without this package depending on x/text.
+type Collator interface {
+	// CompareString returns -1, 0 or +1 as a sorts before, with or after b
+	CompareString(a, b string) int
+}
+
+// WithCollator orders strings with c rather than bytewise wherever they are sorted:
+// in AllSorted, CompareOffsets and Codes.SortOrder. Use it for dictionaries shown to
+// users, who expect "Äpfel" among the other words starting with A. A collator is
+// typically much slower than a bytewise comparison, and collate.Collator isn't safe for
+// concurrent use, so don't sort in more than one goroutine at once with it.
+func WithCollator(c Collator) Option {
+	return func(i *Intern) {
+		i.collator = c
+	}
+}
@@ -0,0 +1,41 @@
+package intern_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+// foldCollator stands in for a collate.Collator. It orders strings regardless of case
+// and treats Ä as A
+type foldCollator struct{}
+
+func (foldCollator) CompareString(a, b string) int {
+	fold := strings.NewReplacer("Ä", "a", "ä", "a")
+	return strings.Compare(strings.ToLower(fold.Replace(a)), strings.ToLower(fold.Replace(b)))
+}
+
+func TestWithCollator(t *testing.T) {
+	in := intern.New(16, intern.WithCollator(foldCollator{}))
+	c := in.Codes()
+	c.EncodeColumn([]string{"Zebra", "apple", "Äpfel", "banana"})
+
+	var sorted []string
+	for _, val := range in.AllSorted() {
+		sorted = append(sorted, val)
+	}
+	assert.Equal(t, []string{"Äpfel", "apple", "banana", "Zebra"}, sorted)
+	assert.Equal(t, []int{2, 1, 3, 0}, c.SortOrder())
+	assert.Equal(t, 1, in.CompareOffsets(in.Save("Zebra"), in.Save("banana")))
+
+	// Bytewise, capitals sort first and Ä last
+	in = intern.New(16)
+	in.SaveAll([]string{"Zebra", "apple", "Äpfel", "banana"}, nil)
+	sorted = sorted[:0]
+	for _, val := range in.AllSorted() {
+		sorted = append(sorted, val)
+	}
+	assert.Equal(t, []string{"Zebra", "apple", "banana", "Äpfel"}, sorted)
+}
@@ -0,0 +1,164 @@
+package intern
+
+import (
+	"iter"
+	"slices"
+)
+
+// compaction holds the state of an incremental compaction. It works like a resize: the
+// entries are copied from the old table to a new one a few at a time, but only the live
+// ones are copied, and their strings are copied to the end of the arena as they go. Once
+// every entry has been dealt with, the chunks that held the strings when the compaction
+// started are let go.
+type compaction struct {
+	// live holds the offsets of the strings to keep. Strings found by Save or Lookup
+	// during the compaction are added to it
+	live Set
+	// moved is called for each string copied
+	moved func(old, new int)
+	// pending is the number of entries in the old table that haven't been dealt with
+	pending int
+	// chunks is the number of chunks in use when the compaction started
+	chunks int
+}
+
+// StartCompaction starts reclaiming the space taken by every string apart from those
+// at the offsets yielded by live, like Collect, but without doing all the work at once.
+// A compaction of a large Intern would otherwise stop everything for a long time. Pinned
+// strings are always kept, and so is any string saved or looked up before the
+// compaction reaches it.
+//
+// The work is done in small steps, in the same way as growing the table: each save does
+// a step, and CompactStep does as many as the caller likes. Lookups stay correct
+// throughout. moved is called with the old and new offsets of each string kept as it is
+// copied, and must not modify i. An offset must be translated as soon as it is reported
+// moved, as the old one stops working once the compaction is complete. Strings already
+// returned by Get stay valid. The strings are not copied in the order they were saved, so
+// AllInOrder gives a different order afterwards. Like Collect, the compaction drops any
+// tuples saved with SaveTuple.
+//
+// Any resize or compaction already in progress is completed first. With WithCuckoo the
+// compaction is done in one go, as the cuckoo table has no incremental resize, and so it
+// is while there are Codes, whose offsets must stay small.
+func (i *Intern) StartCompaction(live iter.Seq[int], moved func(old, new int)) {
+	if i.cuckoo != nil || i.codes != nil {
+		for old, offset := range i.Collect(live) {
+			if moved != nil {
+				moved(old, offset)
+			}
+		}
+		return
+	}
+	i.finishMigration()
+
+	defer traceRegion("intern.startCompaction").End()
+	if i.debug != nil {
+		defer i.debugCheck("start compaction")
+	}
+	c := &compaction{moved: moved, pending: i.count, chunks: len(i.arena.chunks)}
+	for offset := range live {
+		c.live.Add(offset)
+	}
+	c.live.Union(&i.pinned)
+
+	// Strings saved from now on go into new chunks, so that none of the old ones are
+	// still in use at the end
+	i.arena.current = nil
+
+	l := 16
+	for l*i.loadLimit()/16 <= c.live.Len() {
+		l *= 2
+	}
+	i.oldTable, i.table = i.table, newTable(l, i.hugePages)
+	// The old table is only read from now on, so a Snapshot can keep sharing it
+	i.shared = false
+	i.tuples, i.tupleSlices = nil, nil
+	i.compact = c
+}
+
+// CompactStep does the next part of a compaction started with StartCompaction. It deals
+// with at most n entries of the table, rounded up to a multiple of 16, and returns true
+// once the compaction is complete.
+func (i *Intern) CompactStep(n int) bool {
+	for k := 0; k < n && i.compact != nil; k += 16 {
+		i.migrate()
+	}
+	return i.compact == nil
+}
+
+// Compacting returns true while a compaction is in progress
+func (i *Intern) Compacting() bool {
+	return i.compact != nil
+}
+
+// relocate deals with an entry of the old table during a compaction. A live string is
+// copied to the end of the arena and added to the new table, while a dead one is dropped.
+func (i *Intern) relocate(s slot) {
+	c := i.compact
+	c.pending--
+	old := s.index - 1
+	if !c.live.Has(old) {
+		i.count--
+		if i.dict != nil {
+			i.dict.drop(old)
+		}
+		i.evicted(old)
+		return
+	}
+
+	i.compactRoom()
+	offset := i.arena.save(i.Get(old))
+	if i.arena.meta {
+		*i.arena.metaByte(offset) = *i.arena.metaByte(old)
+	}
+	i.copyEntryToTable(i.table, offset+1, s.hash)
+	if i.pinned.Has(old) {
+		i.pinned.Remove(old)
+		i.pinned.Add(offset)
+	}
+	if i.dict != nil {
+		i.dict.move(old, offset)
+	}
+	if c.moved != nil {
+		c.moved(old, offset)
+	}
+}
+
+// compactRoom makes sure there is room in the new table for another entry during a
+// compaction, doubling it if need be. The new table is sized for the strings that were
+// live at the start, so it fills up if many more are saved or looked up before the
+// compaction is done. It only holds the entries copied so far, so growing it in one go
+// costs no more than the copying did.
+func (i *Intern) compactRoom() {
+	// Entries the compaction hasn't reached yet aren't in the new table
+	if i.count-i.compact.pending < i.maxEntries() {
+		return
+	}
+	defer traceRegion("intern.grow").End()
+	t := newTable(i.table.len()*2, i.hugePages)
+	for _, s := range i.table.slots {
+		if s.index != 0 {
+			i.copyEntryToTable(t, s.index, s.hash)
+		}
+	}
+	i.table = t
+}
+
+// finishCompaction lets go of the chunks that only hold strings from before the
+// compaction
+func (i *Intern) finishCompaction() {
+	// A Snapshot or a clone may share the list of chunks, so we change a copy
+	chunks := slices.Clone(i.arena.chunks)
+	clear(chunks[:i.compact.chunks])
+	i.arena.chunks = chunks
+	i.arena.released = i.compact.chunks
+	i.compact = nil
+	i.evict.hand = 0
+	if i.fold != nil {
+		// Strings that weren't live have gone
+		i.rebuildFold()
+	}
+	if i.dict != nil {
+		i.dict.retain()
+	}
+}
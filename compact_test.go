@@ -0,0 +1,225 @@
+package intern_test
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+// compactVal makes strings long enough to fill several chunks
+func compactVal(j int) string {
+	return "value-" + strconv.Itoa(j) + strings.Repeat(".", 50)
+}
+
+func TestCompaction(t *testing.T) {
+	in := intern.New(16)
+	var live intern.Set
+	offsets := make(map[string]int)
+	for j := range 10000 {
+		val := compactVal(j)
+		offset := in.Save(val)
+		if j%10 == 0 {
+			live.Add(offset)
+			offsets[val] = offset
+		}
+	}
+	before := in.MemoryUsage()
+
+	var moves int
+	in.StartCompaction(live.All(), func(old, new int) {
+		moves++
+		for val, offset := range offsets {
+			if offset == old {
+				offsets[val] = new
+				return
+			}
+		}
+		t.Errorf("unexpected move of %d", old)
+	})
+	assert.True(t, in.Compacting())
+
+	for steps := 0; !in.CompactStep(100); steps++ {
+		// Lookups stay correct part way through
+		for val, want := range offsets {
+			offset, ok := in.Lookup(val)
+			assert.True(t, ok)
+			assert.Equal(t, want, offset)
+			assert.Equal(t, val, in.Get(offset))
+		}
+		assert.True(t, steps < 1000)
+	}
+	assert.False(t, in.Compacting())
+	assert.Equal(t, 1000, moves)
+	assert.Equal(t, 1000, in.Len())
+
+	after := in.MemoryUsage()
+	assert.True(t, after.Strings < before.Strings)
+	for val, want := range offsets {
+		offset, ok := in.Lookup(val)
+		assert.True(t, ok)
+		assert.Equal(t, want, offset)
+		assert.Equal(t, val, in.Get(offset))
+	}
+	_, ok := in.Lookup(compactVal(1))
+	assert.False(t, ok)
+}
+
+func TestCompactionSaves(t *testing.T) {
+	in := intern.New(16)
+	for j := range 1000 {
+		in.Save(compactVal(j))
+	}
+	current := make(map[string]int)
+	in.StartCompaction(func(yield func(int) bool) {}, func(old, new int) {
+		for val, offset := range current {
+			if offset == old {
+				current[val] = new
+			}
+		}
+	})
+
+	// Every save does some of the work. Strings saved or looked up before the
+	// compaction reaches them are kept, wherever they are
+	for j := 0; in.Compacting(); j++ {
+		val := compactVal(j)
+		if j%2 == 0 {
+			current[val] = in.Save(val)
+		} else if offset, ok := in.Lookup(val); ok {
+			current[val] = offset
+		}
+		assert.True(t, j < 1000)
+	}
+
+	assert.Equal(t, len(current), in.Len())
+	for val, want := range current {
+		offset, ok := in.Lookup(val)
+		assert.True(t, ok, val)
+		assert.Equal(t, want, offset)
+		assert.Equal(t, val, in.Get(offset))
+	}
+}
+
+func TestCompactionTableFills(t *testing.T) {
+	in := intern.New(16)
+	var live intern.Set
+	for j := range 1000 {
+		offset := in.Save(compactVal(j))
+		if j < 10 {
+			live.Add(offset)
+		}
+	}
+	in.StartCompaction(live.All(), func(old, new int) {})
+
+	// The new table is sized for the 10 live strings, so has to grow to hold more
+	for j := 1000; in.Compacting(); j++ {
+		in.Save(compactVal(j))
+		assert.True(t, j < 2000)
+	}
+	assert.True(t, in.Len() > 10)
+	for j := range 10 {
+		_, ok := in.Lookup(compactVal(j))
+		assert.True(t, ok)
+	}
+	_, ok := in.Lookup(compactVal(10))
+	assert.False(t, ok)
+}
+
+func TestCompactionPinned(t *testing.T) {
+	in := intern.New(16, intern.WithEviction(intern.EvictLFU))
+	pinned := in.Save("pinned")
+	in.Pin(pinned)
+	in.Save("other")
+
+	in.StartCompaction(func(yield func(int) bool) {}, func(old, new int) {
+		assert.Equal(t, pinned, old)
+		pinned = new
+	})
+	assert.True(t, in.CompactStep(1<<20))
+	assert.Equal(t, 1, in.Len())
+	assert.True(t, in.Pinned(pinned))
+	assert.Equal(t, "pinned", in.Get(pinned))
+}
+
+func TestCompactionCuckoo(t *testing.T) {
+	in := intern.New(16, intern.WithCuckoo())
+	hat := in.Save("hat")
+	in.Save("scarf")
+
+	in.StartCompaction(func(yield func(int) bool) { yield(hat) }, func(old, new int) {
+		assert.Equal(t, hat, old)
+		hat = new
+	})
+	assert.False(t, in.Compacting())
+	assert.Equal(t, 1, in.Len())
+	assert.Equal(t, "hat", in.Get(hat))
+}
+
+func TestCompactionSnapshot(t *testing.T) {
+	in := intern.New(16)
+	var live intern.Set
+	for j := range 1000 {
+		offset := in.Save(compactVal(j))
+		if j%2 == 0 {
+			live.Add(offset)
+		}
+	}
+	in.StartCompaction(live.All(), func(old, new int) {})
+	in.CompactStep(512)
+	snap := in.Snapshot()
+	for in.Compacting() {
+		in.CompactStep(16)
+	}
+	in.Save("new")
+
+	// The snapshot still sees the strings that were present when it was taken
+	for j := range 1000 {
+		offset, ok := snap.Lookup(compactVal(j))
+		if ok {
+			assert.Equal(t, compactVal(j), snap.Get(offset))
+		} else {
+			assert.True(t, j%2 == 1)
+		}
+	}
+	_, ok := snap.Lookup("new")
+	assert.False(t, ok)
+}
+
+func TestCollectDuringCompaction(t *testing.T) {
+	in := intern.New(16)
+	var offsets []int
+	for j := range 100 {
+		offsets = append(offsets, in.Save(compactVal(j)))
+	}
+	// Keep two strings through the compaction, following them as they move
+	current := map[int]int{offsets[50]: offsets[50], offsets[99]: offsets[99]}
+	in.StartCompaction(slices.Values([]int{offsets[50], offsets[99]}), func(old, new int) {
+		for k, v := range current {
+			if v == old {
+				current[k] = new
+			}
+		}
+	})
+	in.CompactStep(16)
+	assert.True(t, in.Compacting())
+
+	in.Collect(slices.Values([]int{current[offsets[99]]}))
+	assert.False(t, in.Compacting())
+	var got []string
+	for _, val := range in.All() {
+		got = append(got, val)
+	}
+	assert.Equal(t, []string{compactVal(99)}, got)
+
+	in.Save("new")
+	got = got[:0]
+	for _, val := range in.All() {
+		got = append(got, val)
+	}
+	assert.ElementsMatch(t, []string{compactVal(99), "new"}, got)
+	_, ok := in.Lookup(compactVal(50))
+	assert.False(t, ok)
+}
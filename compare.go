@@ -0,0 +1,34 @@
+package intern
+
+// Equal returns true if a and b hold the same strings. The offsets of the strings don't
+// need to match, so two interners that saw the same strings in a different order, or with
+// different options, are equal.
+func Equal(a, b *Intern) bool {
+	if a.count != b.count {
+		return false
+	}
+	for _, val := range a.All() {
+		if _, ok := b.find(val); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Diff returns the strings held in a but not b, and those held in b but not a, each in
+// offset order, as AllInOrder gives them. It helps check that two interners built
+// independently, such as a primary and a replica, have converged.
+func Diff(a, b *Intern) (onlyA, onlyB []string) {
+	return missingFrom(a, b), missingFrom(b, a)
+}
+
+// missingFrom returns the strings in a that aren't in b
+func missingFrom(a, b *Intern) []string {
+	var missing []string
+	for _, val := range a.AllInOrder() {
+		if _, ok := b.find(val); !ok {
+			missing = append(missing, val)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,35 @@
+package intern_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEqual(t *testing.T) {
+	a := intern.New(16)
+	b := intern.New(16, intern.WithCuckoo())
+	for j := 0; j < 1000; j++ {
+		a.Save(strconv.Itoa(j))
+		b.Save(strconv.Itoa(999 - j))
+	}
+	assert.True(t, intern.Equal(a, b))
+	onlyA, onlyB := intern.Diff(a, b)
+	assert.Empty(t, onlyA)
+	assert.Empty(t, onlyB)
+
+	a.Save("hat")
+	a.Save("sat")
+	b.Save("mat")
+	assert.False(t, intern.Equal(a, b))
+	onlyA, onlyB = intern.Diff(a, b)
+	assert.Equal(t, []string{"hat", "sat"}, onlyA)
+	assert.Equal(t, []string{"mat"}, onlyB)
+
+	// Same size, different strings
+	b.Save("cat")
+	assert.False(t, intern.Equal(a, b))
+	assert.True(t, intern.Equal(&intern.Intern{}, &intern.Intern{}))
+}
@@ -0,0 +1,38 @@
+package intern
+
+// CompressionDictionary returns up to size bytes of stored strings, for use as a preset
+// dictionary when compressing data that contains them. It suits compress/flate's
+// NewWriterDict and NewReaderDict, which make use of the last 32KB, and zstd, which takes
+// any byte slice as a raw content dictionary. The interner already holds exactly the
+// repetitive text such a dictionary should contain, so there is nothing to train.
+//
+// If the strings don't all fit, an even sample is taken across them in offset order, so
+// that strings from every part of the input are represented. Strings are concatenated
+// without separators, in the order AllInOrder gives them.
+func (i *Intern) CompressionDictionary(size int) []byte {
+	if size <= 0 || i.count == 0 {
+		return nil
+	}
+
+	var total int
+	for _, val := range i.AllInOrder() {
+		total += len(val)
+	}
+
+	// Take every stride'th string, so that the sample adds up to about size bytes
+	stride := max(1, (total+size-1)/size)
+	dict := make([]byte, 0, min(size, total))
+	var j int
+	for _, val := range i.AllInOrder() {
+		j++
+		if (j-1)%stride != 0 {
+			continue
+		}
+		if len(dict)+len(val) > size {
+			dict = append(dict, val[:size-len(dict)]...)
+			break
+		}
+		dict = append(dict, val...)
+	}
+	return dict
+}
@@ -0,0 +1,66 @@
+package intern_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressionDictionary(t *testing.T) {
+	var in intern.Intern
+	assert.Nil(t, in.CompressionDictionary(100))
+
+	in.Save("hat")
+	in.Save("scarf")
+	in.Save("gloves")
+	assert.Equal(t, "hatscarfgloves", string(in.CompressionDictionary(100)))
+	// Every other string is taken, and the last is cut short
+	assert.Equal(t, "hatglove", string(in.CompressionDictionary(8)))
+	assert.Nil(t, in.CompressionDictionary(0))
+}
+
+func TestCompressionDictionarySample(t *testing.T) {
+	var in intern.Intern
+	for j := range 10000 {
+		in.Save("value-" + strconv.Itoa(j))
+	}
+
+	dict := in.CompressionDictionary(1000)
+	assert.True(t, len(dict) > 900 && len(dict) <= 1000)
+	// The sample should come from across the whole input
+	assert.True(t, bytes.HasPrefix(dict, []byte("value-0")))
+	assert.Contains(t, string(dict), "value-99")
+}
+
+func TestCompressionDictionaryFlate(t *testing.T) {
+	var in intern.Intern
+	words := []string{"application/json", "text/html; charset=utf-8", "Mozilla/5.0 (X11; Linux x86_64)", "gzip, deflate, br"}
+	for _, w := range words {
+		in.Save(w)
+	}
+	dict := in.CompressionDictionary(32 * 1024)
+
+	msg := strings.Join(words, "\n")
+	compress := func(dict []byte) []byte {
+		var b bytes.Buffer
+		w, err := flate.NewWriterDict(&b, flate.BestCompression, dict)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(msg))
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+		return b.Bytes()
+	}
+
+	withDict := compress(dict)
+	assert.True(t, len(withDict) < len(compress(nil)))
+
+	out, err := io.ReadAll(flate.NewReaderDict(bytes.NewReader(withDict), dict))
+	assert.NoError(t, err)
+	assert.Equal(t, msg, string(out))
+}
@@ -0,0 +1,183 @@
+package intern
+
+import "unsafe"
+
+const (
+	// cuckooBucketSize is the number of slots in each cuckoo bucket. Four 16-byte slots
+	// fill a 64-byte cache line
+	cuckooBucketSize = 4
+	// cuckooMaxKicks limits how many entries an insert may move before we give up and
+	// grow the table
+	cuckooMaxKicks = 500
+	// cuckooLoadLimit is the load factor at which the table grows, in sixteenths. Buckets
+	// of four slots let a cuckoo table fill much further than linear probing
+	cuckooLoadLimit = 14
+)
+
+// cuckooTable is an alternative to the linear-probing table, selected with WithCuckoo.
+// Each entry lives in one of two buckets chosen by its hash, so a lookup never examines
+// more than two buckets. An insert that finds both its buckets full moves an existing
+// entry to that entry's other bucket, which may in turn displace another. If that goes on
+// for too long the table is doubled and rebuilt in one go.
+type cuckooTable struct {
+	// slots holds the buckets one after another
+	slots []slot
+	// kick picks which slot of a full bucket is displaced next. Rotating through them
+	// stops an insert from bouncing the same two entries back and forth
+	kick int
+}
+
+// newCuckooTable makes a table with room for at least l slots
+func newCuckooTable(l int, huge bool) *cuckooTable {
+	return &cuckooTable{slots: allocSlots(max(l, 2*cuckooBucketSize), huge)}
+}
+
+// len returns the number of slots in the table
+func (c *cuckooTable) len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.slots)
+}
+
+// bytes returns the memory used by the table
+func (c *cuckooTable) bytes() int {
+	return c.len() * int(unsafe.Sizeof(slot{}))
+}
+
+// table returns the slots as a table, so that code that only visits every entry can
+// treat both kinds of table alike
+func (c *cuckooTable) table() table {
+	if c == nil {
+		return table{}
+	}
+	return table{slots: c.slots}
+}
+
+// bucket returns the slots of bucket b
+func (c *cuckooTable) bucket(b int) []slot {
+	return c.slots[b*cuckooBucketSize : (b+1)*cuckooBucketSize]
+}
+
+// add stores s in an empty slot of bucket b, if there is one
+func (c *cuckooTable) add(b int, s slot) bool {
+	bucket := c.bucket(b)
+	for j := range bucket {
+		if bucket[j].index == 0 {
+			bucket[j] = s
+			return true
+		}
+	}
+	return false
+}
+
+// cuckooBuckets returns the two buckets an entry with the given hash may be stored in
+func (i *Intern) cuckooBuckets(hash uint64) (b1, b2 int) {
+	mask := len(i.cuckoo.slots)/cuckooBucketSize - 1
+	h := i.spread(hash)
+	b1 = int(h) & mask
+	b2 = int(h>>32) & mask
+	if b2 == b1 {
+		// Every entry needs two different buckets, or it could never be moved
+		b2 = b1 ^ 1
+	}
+	return b1, b2
+}
+
+// cuckooFind looks for val in the cuckoo table. It returns the arena offset of the
+// string + 1, or 0 if the string isn't present
+func (i *Intern) cuckooFind(val string, hash uint64) int {
+	b1, b2 := i.cuckooBuckets(hash)
+	for probes, b := range [2]int{b1, b2} {
+		for _, s := range i.cuckoo.bucket(b) {
+			if s.hash == hash && s.index != 0 && (isInline(hash) || i.Get(s.index-1) == val) {
+				if i.probes.sampleEvery != 0 {
+					i.probes.record(probes + 1)
+				}
+				return s.index
+			}
+		}
+	}
+	if i.probes.sampleEvery != 0 {
+		i.probes.record(2)
+	}
+	return 0
+}
+
+// cuckooInsert adds an entry that is known not to be in the table, growing the table if
+// it is getting full or the entry can't be placed.
+func (i *Intern) cuckooInsert(s slot) {
+	if i.count >= i.maxEntries() {
+		i.cuckooRebuild(i.cuckoo.len()*2, i.cuckooEntries(s))
+		return
+	}
+	if homeless, ok := i.cuckooPlace(s); !ok {
+		i.cuckooRebuild(i.cuckoo.len()*2, i.cuckooEntries(homeless))
+	}
+}
+
+// cuckooPlace stores s in the table, moving other entries as needed. If it runs out of
+// moves it returns whichever entry was left without a home.
+func (i *Intern) cuckooPlace(s slot) (slot, bool) {
+	c := i.cuckoo
+	b1, b2 := i.cuckooBuckets(s.hash)
+	if c.add(b1, s) || c.add(b2, s) {
+		return slot{}, true
+	}
+
+	b := b1
+	for kicks := 0; kicks < cuckooMaxKicks; kicks++ {
+		// Swap s for an entry in bucket b, then try to put that entry in its other bucket
+		bucket := c.bucket(b)
+		victim := c.kick % cuckooBucketSize
+		c.kick++
+		s, bucket[victim] = bucket[victim], s
+
+		v1, v2 := i.cuckooBuckets(s.hash)
+		if b == v1 {
+			b = v2
+		} else {
+			b = v1
+		}
+		if c.add(b, s) {
+			return slot{}, true
+		}
+	}
+	return s, false
+}
+
+// cuckooEntries returns every entry in the table, plus extra
+func (i *Intern) cuckooEntries(extra slot) []slot {
+	entries := make([]slot, 0, i.count+1)
+	for _, s := range i.cuckoo.slots {
+		if s.index != 0 {
+			entries = append(entries, s)
+		}
+	}
+	return append(entries, extra)
+}
+
+// cuckooRebuild replaces the cuckoo table with one of at least l slots holding entries.
+// The table keeps doubling until every entry fits.
+func (i *Intern) cuckooRebuild(l int, entries []slot) {
+	defer traceRegion("intern.cuckooRebuild").End()
+	for ; ; l *= 2 {
+		i.cuckoo = newCuckooTable(l, i.hugePages)
+		if i.cuckooPlaceAll(entries) {
+			break
+		}
+	}
+	if i.filter != nil {
+		i.rebuildFilter()
+	}
+}
+
+// cuckooPlaceAll stores each entry in the table, returning false if one can't be placed
+func (i *Intern) cuckooPlaceAll(entries []slot) bool {
+	for _, s := range entries {
+		if _, ok := i.cuckooPlace(s); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,100 @@
+package intern_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCuckoo(t *testing.T) {
+	in := intern.New(16, intern.WithCuckoo(), intern.WithProbeStats(1))
+
+	offsets := make(map[string]int)
+	for j := 0; j < 10000; j++ {
+		val := strconv.Itoa(j) + "-cuckoo"
+		offsets[val] = in.Save(val)
+	}
+	assert.Equal(t, 10000, in.Len())
+	assert.True(t, in.Cap() >= 10000, in.Cap())
+
+	for val, offset := range offsets {
+		assert.Equal(t, offset, in.Save(val))
+		assert.Equal(t, val, in.Get(offset))
+	}
+	_, ok := in.Lookup("missing")
+	assert.False(t, ok)
+
+	// No lookup examines more than two buckets
+	lengths := in.Stats().ProbeLengths
+	assert.NotZero(t, lengths[0])
+	for k, count := range lengths[2:] {
+		assert.Zero(t, count, k+2)
+	}
+
+	in.Rehash()
+	for val, offset := range offsets {
+		found, ok := in.Lookup(val)
+		assert.True(t, ok, val)
+		assert.Equal(t, offset, found)
+	}
+
+	var seen int
+	for offset, val := range in.All() {
+		assert.Equal(t, offsets[val], offset)
+		seen++
+	}
+	assert.Equal(t, 10000, seen)
+
+	var b strings.Builder
+	assert.NoError(t, in.Dump(&b))
+	assert.Contains(t, b.String(), "cuckoo hashing: ")
+}
+
+func TestCuckooOptions(t *testing.T) {
+	in := intern.New(16, intern.WithCuckoo(), intern.WithBloomFilter(), intern.WithMaxBytes(1<<19))
+
+	var stored int
+	for j := 0; j < 100000; j++ {
+		if _, err := in.TrySave(strconv.Itoa(j)); err != nil {
+			assert.Equal(t, intern.ErrMaxBytes, err)
+			break
+		}
+		stored++
+	}
+	assert.Equal(t, stored, in.Len())
+	assert.True(t, stored < 100000, stored)
+	assert.True(t, in.MemoryUsage().Total() <= 1<<19+1<<18, in.MemoryUsage())
+
+	for j := 0; j < stored; j++ {
+		_, ok := in.Lookup(strconv.Itoa(j))
+		assert.True(t, ok, j)
+	}
+}
+
+func BenchmarkCuckooLookup(b *testing.B) {
+	for _, test := range []struct {
+		name string
+		opts []intern.Option
+	}{
+		{"linear", nil},
+		{"cuckoo", []intern.Option{intern.WithCuckoo()}},
+	} {
+		b.Run(test.name, func(b *testing.B) {
+			in := intern.New(16, test.opts...)
+			vals := make([]string, 1000000)
+			for j := range vals {
+				vals[j] = strconv.Itoa(j) + "-cuckoo"
+				in.Save(vals[j])
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for j := 0; j < b.N; j++ {
+				in.Lookup(vals[j%len(vals)])
+			}
+		})
+	}
+}
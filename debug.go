@@ -0,0 +1,109 @@
+package intern
+
+import (
+	"fmt"
+	"strings"
+)
+
+// debugSweep is the number of table slots checked after each operation in debug mode
+const debugSweep = 16
+
+// debugState is kept by an Intern created with WithDebug
+type debugState struct {
+	// history holds the most recent operations, oldest first once it has wrapped round
+	history []debugOp
+	next    int
+	wrapped bool
+	// sweep is the next table slot to check
+	sweep int
+}
+
+// debugOp remembers one operation
+type debugOp struct {
+	name   string
+	val    string
+	offset int
+}
+
+// WithDebug makes the Intern check itself as it goes, to catch corruption as close as
+// possible to the operation that causes it. After each save or lookup the string involved
+// is looked up again, and a few more slots of the table are checked in turn so that the
+// whole table is covered every so often. CheckInvariants is run in full after each resize,
+// compaction, Collect, Evict, Rehash and Reserve. The last history operations are
+// remembered, and if a check fails the Intern panics with an error wrapping ErrCorrupt
+// that lists them. This slows every operation down, and full checks take time in
+// proportion to the number of strings.
+func WithDebug(history int) Option {
+	return func(i *Intern) {
+		i.debug = &debugState{history: make([]debugOp, max(history, 1))}
+	}
+}
+
+// debugRecord remembers an operation. Only the start of long strings is kept
+func (i *Intern) debugRecord(name, val string, offset int) {
+	d := i.debug
+	if len(val) > dumpMaxString {
+		val = val[:dumpMaxString]
+	}
+	d.history[d.next] = debugOp{name: name, val: strings.Clone(val), offset: offset}
+	if d.next++; d.next == len(d.history) {
+		d.next, d.wrapped = 0, true
+	}
+}
+
+// debugSaved checks a string that has just been saved or looked up, then moves the
+// sweep through the table on
+func (i *Intern) debugSaved(name, val string, offset int) {
+	i.debugRecord(name, val, offset)
+	if got, ok := i.find(val); !ok || got != offset {
+		i.debugFail(fmt.Errorf("%w: %s is at offset %d, but looking it up finds %d", ErrCorrupt, dumpString(val), offset, got))
+	}
+	if got := i.Get(offset); got != val {
+		i.debugFail(fmt.Errorf("%w: %s is at offset %d, which holds %s", ErrCorrupt, dumpString(val), offset, dumpString(got)))
+	}
+
+	t := i.table
+	if i.cuckoo != nil {
+		t = i.cuckoo.table()
+	}
+	for range debugSweep {
+		pos := i.debug.sweep & (t.len() - 1)
+		i.debug.sweep++
+		s := t.slots[pos]
+		if s.index == 0 {
+			continue
+		}
+		if err := i.checkSlot(s); err != nil {
+			i.debugFail(fmt.Errorf("%w: table slot %d: %w", ErrCorrupt, pos, err))
+		}
+	}
+}
+
+// debugCheck records an operation that reorganises the table, then checks everything
+func (i *Intern) debugCheck(name string) {
+	i.debugRecord(name, "", 0)
+	if err := i.CheckInvariants(); err != nil {
+		i.debugFail(err)
+	}
+}
+
+// debugFail panics with err and the operations leading up to it
+func (i *Intern) debugFail(err error) {
+	d := i.debug
+	ops := d.history[:d.next]
+	if d.wrapped {
+		ops = append(d.history[d.next:], d.history[:d.next]...)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "last %d operations, most recent last:", len(ops))
+	for _, op := range ops {
+		if op.name == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n  %s", op.name)
+		if op.val != "" || op.offset != 0 {
+			fmt.Fprintf(&b, " %q at offset %d", op.val, op.offset)
+		}
+	}
+	panic(fmt.Errorf("%w\n%s", err, b.String()))
+}
@@ -0,0 +1,54 @@
+package intern_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebug(t *testing.T) {
+	for _, opts := range [][]intern.Option{{intern.WithDebug(10)}, {intern.WithDebug(10), intern.WithCuckoo()}} {
+		in := intern.New(16, opts...)
+		var live intern.Set
+		for j := range 500 {
+			offset := in.Save("value-" + strconv.Itoa(j))
+			if j%2 == 0 {
+				live.Add(offset)
+			}
+		}
+		_, ok := in.Lookup("value-3")
+		assert.True(t, ok)
+		in.Reserve(1000)
+		in.Rehash()
+		in.Collect(live.All())
+		assert.Equal(t, 250, in.Len())
+	}
+}
+
+func TestDebugCorrupt(t *testing.T) {
+	in := intern.New(16, intern.WithDebug(3))
+	for j := range 100 {
+		in.Save("a longer value " + strconv.Itoa(j))
+	}
+	offset, _ := in.Lookup("a longer value 42")
+	in.GetBytes(offset)[0] = 'A'
+
+	var err error
+	func() {
+		defer func() {
+			err, _ = recover().(error)
+		}()
+		// The sweep reaches the damaged entry within one pass of the table
+		for j := range in.Cap() {
+			in.Save("a longer value " + strconv.Itoa(j))
+		}
+	}()
+	if assert.Error(t, err) {
+		assert.True(t, errors.Is(err, intern.ErrCorrupt))
+		assert.Contains(t, err.Error(), `"A longer value 42", which hashes to`)
+		assert.Contains(t, err.Error(), "last 3 operations, most recent last:\n  save (found) \"a longer value ")
+	}
+}
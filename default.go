@@ -0,0 +1,31 @@
+package intern
+
+// defaultShared is the interner used by the package-level functions. Its tables are
+// allocated on first use.
+var defaultShared Shared
+
+// Deduplicate returns a permanently stored version of val from a package-level interner
+// shared by the whole program. It is safe to call from multiple goroutines, and saves
+// passing an *Intern around in programs that just want to avoid holding many copies of
+// the same strings.
+func Deduplicate(val string) string {
+	return defaultShared.Deduplicate(val)
+}
+
+// DeduplicateBytes is like Deduplicate, but takes the string as a byte slice, which is
+// only read
+func DeduplicateBytes(b []byte) string {
+	return defaultShared.DeduplicateBytes(b)
+}
+
+// Save stores val in the package-level interner and returns its offset, which Get turns
+// back into the string. It is safe to call from multiple goroutines.
+func Save(val string) int {
+	return defaultShared.Save(val)
+}
+
+// Get returns the string stored in the package-level interner at offset, which must have
+// come from Save. It is safe to call from multiple goroutines.
+func Get(offset int) string {
+	return defaultShared.Get(offset)
+}
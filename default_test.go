@@ -0,0 +1,40 @@
+package intern_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefault(t *testing.T) {
+	hat := intern.Save("hat")
+	assert.Equal(t, "hat", intern.Get(hat))
+	assert.Equal(t, hat, intern.Save("hat"))
+	assert.Equal(t, datapointer(intern.Get(hat)), datapointer(intern.Deduplicate("hat")))
+}
+
+func TestDefaultConcurrent(t *testing.T) {
+	var wg sync.WaitGroup
+	offsets := make([][]int, 8)
+	for g := range offsets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10000; j++ {
+				offsets[g] = append(offsets[g], intern.Save("default"+strconv.Itoa(j)))
+				intern.Deduplicate(strconv.Itoa(j))
+			}
+		}()
+	}
+	wg.Wait()
+
+	for g := range offsets {
+		assert.Equal(t, offsets[0], offsets[g])
+	}
+	for j, offset := range offsets[0] {
+		assert.Equal(t, "default"+strconv.Itoa(j), intern.Get(offset))
+	}
+}
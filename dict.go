@@ -0,0 +1,117 @@
+package intern
+
+// Dict is a dictionary encoding view of an Intern. It numbers the strings 0, 1, 2 and so
+// on in the order they are stored, which is what columnar formats expect of a
+// dictionary. The numbering is a stable bijection: every string has exactly one ID,
+// which never changes while the strings stay put, and IDs run from 0 to Len()-1 without
+// gaps.
+//
+// Collect, Evict and compaction drop strings, so when they have done their work the
+// strings that are left are numbered afresh from 0, in the order of their old IDs. An ID
+// only changes if a string with a lower ID was dropped, and IDs from before must be
+// looked up again, just as offsets must be translated.
+type Dict struct {
+	in *Intern
+	// offsets maps each ID to the string's offset in the Intern, and ids the other way
+	offsets []int
+	ids     map[int]int
+}
+
+// Dict returns the dictionary view of i, making it on the first call. Strings already
+// stored get IDs in offset order, as AllInOrder gives them, and strings stored
+// afterwards, whether through the Dict or directly, get the next IDs in turn. Every call
+// returns the same Dict.
+func (i *Intern) Dict() *Dict {
+	if i.dict == nil {
+		d := &Dict{in: i, ids: make(map[int]int, i.count)}
+		for offset := range i.AllInOrder() {
+			d.add(offset)
+		}
+		i.dict = d
+	}
+	return i.dict
+}
+
+// ToID returns the ID of val, storing it if it is new. Like Save, it panics if val is
+// new and storing it would exceed the memory budget.
+func (d *Dict) ToID(val string) int {
+	return d.ids[d.in.Save(val)]
+}
+
+// LookupID returns the ID of val and true if it is present, without storing it, or
+// InvalidOffset and false if not. InvalidOffset is never an ID.
+func (d *Dict) LookupID(val string) (int, bool) {
+	offset, ok := d.in.Lookup(val)
+	if !ok {
+		return InvalidOffset, false
+	}
+	id, ok := d.ids[offset]
+	if !ok {
+		return InvalidOffset, false
+	}
+	return id, true
+}
+
+// FromID returns the string with the given ID
+func (d *Dict) FromID(id int) string {
+	return d.in.Get(d.offsets[id])
+}
+
+// Len returns the number of strings in the dictionary
+func (d *Dict) Len() int {
+	return len(d.offsets)
+}
+
+// add gives the string at offset the next ID
+func (d *Dict) add(offset int) {
+	d.ids[offset] = len(d.offsets)
+	d.offsets = append(d.offsets, offset)
+}
+
+// move records that compaction has copied a string from old to new. It keeps its ID
+func (d *Dict) move(old, new int) {
+	if id, ok := d.ids[old]; ok {
+		delete(d.ids, old)
+		d.ids[new] = id
+		d.offsets[id] = new
+	}
+}
+
+// drop records that compaction has dropped the string at offset. Its ID still works
+// until the compaction is complete and renumber is called, as the string's memory is
+// kept until then
+func (d *Dict) drop(offset int) {
+	delete(d.ids, offset)
+}
+
+// renumber gives the strings that are still stored IDs from 0, in the order of their
+// old ones. current returns the string's offset now given its offset before, and false
+// if it has been dropped.
+func (d *Dict) renumber(current func(offset int) (int, bool)) {
+	ids := make(map[int]int, len(d.ids))
+	offsets := d.offsets[:0]
+	for _, offset := range d.offsets {
+		if offset, ok := current(offset); ok {
+			ids[offset] = len(offsets)
+			offsets = append(offsets, offset)
+		}
+	}
+	d.offsets, d.ids = offsets, ids
+}
+
+// remap renumbers the strings once rebuild has moved them as described by remap
+func (d *Dict) remap(remap map[int]int) {
+	d.renumber(func(offset int) (int, bool) {
+		offset, ok := remap[offset]
+		return offset, ok
+	})
+}
+
+// retain renumbers the strings once a compaction is complete, dropping those that
+// weren't kept
+func (d *Dict) retain() {
+	d.renumber(func(offset int) (int, bool) {
+		_, ok := d.ids[offset]
+		return offset, ok
+	})
+}
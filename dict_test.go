@@ -0,0 +1,99 @@
+package intern_test
+
+import (
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDict(t *testing.T) {
+	in := intern.New(16)
+	in.Save("zero")
+	in.Save("one")
+
+	d := in.Dict()
+	assert.Equal(t, 2, d.Len())
+	assert.Equal(t, 0, d.ToID("zero"))
+	assert.Equal(t, 1, d.ToID("one"))
+	assert.Equal(t, 2, d.ToID("two"))
+
+	// Strings saved directly get IDs too
+	in.Save("three")
+	id, ok := d.LookupID("three")
+	assert.True(t, ok)
+	assert.Equal(t, 3, id)
+	id, ok = d.LookupID("four")
+	assert.False(t, ok)
+	assert.Equal(t, intern.InvalidOffset, id)
+
+	for j := 4; j < 1000; j++ {
+		assert.Equal(t, j, d.ToID(strconv.Itoa(j)))
+	}
+	assert.Equal(t, 1000, d.Len())
+	for j := 0; j < d.Len(); j++ {
+		assert.Equal(t, j, d.ToID(d.FromID(j)))
+	}
+	assert.Equal(t, "two", d.FromID(2))
+}
+
+func TestDictSame(t *testing.T) {
+	in := intern.New(16)
+	assert.True(t, in.Dict() == in.Dict())
+}
+
+func TestDictCollect(t *testing.T) {
+	in := intern.New(16)
+	d := in.Dict()
+	for j := 0; j < 100; j++ {
+		d.ToID(strconv.Itoa(j))
+	}
+	var live []int
+	for j := 0; j < 100; j += 3 {
+		live = append(live, in.Save(strconv.Itoa(j)))
+	}
+	in.Collect(slices.Values(live))
+
+	// The strings left are numbered afresh in their old order
+	assert.Equal(t, 34, d.Len())
+	for id := 0; id < d.Len(); id++ {
+		assert.Equal(t, strconv.Itoa(id*3), d.FromID(id))
+		assert.Equal(t, id, d.ToID(d.FromID(id)))
+	}
+	_, ok := d.LookupID("1")
+	assert.False(t, ok)
+	assert.Equal(t, 34, d.ToID("new"))
+}
+
+func TestDictCompaction(t *testing.T) {
+	in := intern.New(16)
+	d := in.Dict()
+	for j := 0; j < 100; j++ {
+		d.ToID(strconv.Itoa(j))
+	}
+	var live []int
+	for j := 0; j < 100; j += 3 {
+		live = append(live, in.Save(strconv.Itoa(j)))
+	}
+	in.StartCompaction(slices.Values(live), nil)
+	var saved []string
+	for j := 0; !in.CompactStep(16); j++ {
+		// IDs stay right while the strings are moved
+		assert.Equal(t, 99, d.ToID("99"))
+		val := "new" + strconv.Itoa(j)
+		assert.Equal(t, 100+j, d.ToID(val))
+		saved = append(saved, val)
+	}
+
+	assert.Equal(t, 34+len(saved), d.Len())
+	for id := 0; id < 34; id++ {
+		assert.Equal(t, strconv.Itoa(id*3), d.FromID(id))
+	}
+	for j, val := range saved {
+		assert.Equal(t, 34+j, d.ToID(val))
+	}
+	_, ok := d.LookupID("1")
+	assert.False(t, ok)
+}
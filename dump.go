@@ -0,0 +1,127 @@
+package intern
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+)
+
+const (
+	// dumpWidth is the number of cells in each row of the occupancy map
+	dumpWidth = 64
+	// dumpRows is the maximum number of rows in the occupancy map
+	dumpRows = 16
+	// dumpTop is how many of the longest probe chains and strings are listed
+	dumpTop = 10
+	// dumpMaxString is how much of each listed string is shown
+	dumpMaxString = 60
+)
+
+// occupancyChars shows how full each cell of the occupancy map is, from empty to full
+const occupancyChars = " .:-=+*#%@"
+
+// Dump writes a human-readable report on the state of the hash table to w. It includes a
+// map of how full each part of the table is, the entries furthest from their home slots
+// and the largest strings stored. It is intended to help diagnose poor performance.
+func (i *Intern) Dump(w io.Writer) error {
+	var b bytes.Buffer
+	t := i.table
+	if i.cuckoo != nil {
+		t = i.cuckoo.table()
+	}
+	l := t.len()
+
+	fmt.Fprintf(&b, "entries: %d\n", i.count)
+	fmt.Fprintf(&b, "table size: %d\n", l)
+	if i.cuckoo != nil {
+		fmt.Fprintf(&b, "cuckoo hashing: %d buckets of %d slots\n", l/cuckooBucketSize, cuckooBucketSize)
+	}
+	if l != 0 {
+		fmt.Fprintf(&b, "load factor: %.3f\n", float64(i.count)/float64(l))
+	}
+	if i.compact != nil {
+		fmt.Fprintf(&b, "compaction in progress: %d of %d old slots examined\n", i.oldTableCursor, i.oldTable.len())
+	} else if i.oldTable.len() != 0 {
+		fmt.Fprintf(&b, "resize in progress: %d of %d old slots copied\n", i.oldTableCursor, i.oldTable.len())
+	}
+	m := i.MemoryUsage()
+	fmt.Fprintf(&b, "memory: %d bytes (table %d, old table %d, strings %d)\n", m.Total(), m.Table, m.OldTable, m.Strings)
+
+	if l == 0 {
+		_, err := w.Write(b.Bytes())
+		return err
+	}
+
+	// Occupancy map. Each cell covers a run of slots
+	cells := min(l, dumpWidth*dumpRows)
+	perCell := l / cells
+	fmt.Fprintf(&b, "\noccupancy (%d slots per cell):\n", perCell)
+	for cell := 0; cell < cells; cell++ {
+		var used int
+		for _, s := range t.slots[cell*perCell : (cell+1)*perCell] {
+			if s.index != 0 {
+				used++
+			}
+		}
+		b.WriteByte(occupancyChars[used*(len(occupancyChars)-1)/perCell])
+		if (cell+1)%dumpWidth == 0 || cell == cells-1 {
+			b.WriteByte('\n')
+		}
+	}
+
+	type entry struct {
+		slot     int
+		distance int
+		offset   int
+		length   int
+	}
+	var entries []entry
+	for pos, s := range t.slots {
+		if s.index == 0 {
+			continue
+		}
+		home := int(i.spread(s.hash)) & (l - 1)
+		distance := (pos - home) & (l - 1)
+		if i.cuckoo != nil {
+			// Entries are either in their first bucket or their second
+			distance = 0
+			if b1, _ := i.cuckooBuckets(s.hash); pos/cuckooBucketSize != b1 {
+				distance = 1
+			}
+		}
+		entries = append(entries, entry{
+			slot:     pos,
+			distance: distance,
+			offset:   s.index - 1,
+			length:   len(i.Get(s.index - 1)),
+		})
+	}
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		return b.distance - a.distance
+	})
+	fmt.Fprintf(&b, "\nlongest probe chains:\n")
+	for _, e := range entries[:min(len(entries), dumpTop)] {
+		fmt.Fprintf(&b, "  slot %d: %d slots from home, offset %d %s\n", e.slot, e.distance, e.offset, dumpString(i.Get(e.offset)))
+	}
+
+	slices.SortFunc(entries, func(a, b entry) int {
+		return b.length - a.length
+	})
+	fmt.Fprintf(&b, "\nlargest strings:\n")
+	for _, e := range entries[:min(len(entries), dumpTop)] {
+		fmt.Fprintf(&b, "  offset %d: %d bytes %s\n", e.offset, e.length, dumpString(i.Get(e.offset)))
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// dumpString quotes val, shortening it if it is long
+func dumpString(val string) string {
+	if len(val) > dumpMaxString {
+		return fmt.Sprintf("%q...", val[:dumpMaxString])
+	}
+	return fmt.Sprintf("%q", val)
+}
@@ -0,0 +1,49 @@
+package intern_test
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDump(t *testing.T) {
+	in := intern.New(16)
+	for j := 0; j < 1000; j++ {
+		in.Save(strconv.Itoa(j))
+	}
+	in.Save(strings.Repeat("long", 100))
+
+	var b strings.Builder
+	assert.NoError(t, in.Dump(&b))
+	out := b.String()
+
+	assert.Contains(t, out, "entries: 1001\n")
+	assert.Contains(t, out, "table size: 2048\n")
+	assert.Contains(t, out, "occupancy (2 slots per cell):\n")
+	assert.Contains(t, out, "longest probe chains:\n")
+	assert.Contains(t, out, "largest strings:\n  offset 3890: 400 bytes \"longlong")
+}
+
+func TestDumpEmpty(t *testing.T) {
+	var in intern.Intern
+
+	var b strings.Builder
+	assert.NoError(t, in.Dump(&b))
+	assert.Equal(t, "entries: 0\ntable size: 0\nmemory: 0 bytes (table 0, old table 0, strings 0)\n", b.String())
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("oops")
+}
+
+func TestDumpError(t *testing.T) {
+	in := intern.New(16)
+	in.Save("hat")
+	assert.EqualError(t, in.Dump(errWriter{}), "oops")
+}
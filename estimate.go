@@ -0,0 +1,61 @@
+package intern
+
+import (
+	"math"
+	"math/bits"
+	"unsafe"
+)
+
+// Estimate holds suggested parameters for New, worked out by EstimateCapacity
+type Estimate struct {
+	// Unique is the expected number of unique strings
+	Unique int
+	// Duplication is the fraction of strings in the sample that repeated an earlier one
+	Duplication float64
+	// MeanLength is the mean length in bytes of the unique strings in the sample
+	MeanLength float64
+	// Cap is the capacity to pass to New so that the table never needs to grow
+	Cap int
+	// Bytes is the expected memory used by the table and the strings. It makes a starting
+	// point for WithMaxBytes
+	Bytes int
+}
+
+// EstimateCapacity looks at the duplication rate and string lengths in sample and
+// suggests how to size an Intern that will be shown expectedTotal strings in all. The
+// number of unique strings is scaled up from the sample in proportion, which overestimates
+// it when repeats become more common as more strings are seen, as they usually do. That
+// errs on the side of a table that doesn't need to grow.
+func EstimateCapacity(sample []string, expectedTotal int) Estimate {
+	if len(sample) == 0 {
+		return Estimate{Cap: 16}
+	}
+
+	var a arena
+	seen := make(map[string]struct{}, len(sample))
+	var length, space int
+	for _, val := range sample {
+		if _, ok := seen[val]; ok {
+			continue
+		}
+		seen[val] = struct{}{}
+		length += len(val)
+		space += a.space(len(val))
+	}
+
+	e := Estimate{
+		Duplication: 1 - float64(len(seen))/float64(len(sample)),
+		MeanLength:  float64(length) / float64(len(seen)),
+	}
+	scale := float64(max(expectedTotal, len(sample))) / float64(len(sample))
+	e.Unique = int(math.Ceil(float64(len(seen)) * scale))
+
+	// The table grows once it is 3/4 full, and New rounds the capacity up to a power of 2
+	e.Cap = max(e.Unique*4/3+1, 16)
+	tableSize := 1 << bits.Len(uint(e.Cap-1))
+
+	strings := int(math.Ceil(float64(space) * scale))
+	chunk := a.chunkSize()
+	e.Bytes = tableSize*int(unsafe.Sizeof(slot{})) + (strings+chunk-1)/chunk*chunk
+	return e
+}
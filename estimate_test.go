@@ -0,0 +1,38 @@
+package intern_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateCapacity(t *testing.T) {
+	// 1000 strings, each appearing 4 times
+	sample := make([]string, 0, 4000)
+	for j := 0; j < 4000; j++ {
+		sample = append(sample, "value-"+strconv.Itoa(1000+j%1000))
+	}
+
+	e := intern.EstimateCapacity(sample, 400000)
+	assert.Equal(t, 100000, e.Unique)
+	assert.Equal(t, 0.75, e.Duplication)
+	assert.Equal(t, 10.0, e.MeanLength)
+	assert.Equal(t, 133334, e.Cap)
+	// A 256K slot table, and 11 bytes per string
+	assert.Equal(t, 1<<18*16+5<<18, e.Bytes)
+
+	// Following the advice means the table never grows, and stays within the budget
+	in := intern.New(e.Cap, intern.WithMaxBytes(e.Bytes))
+	cap := in.Cap()
+	for j := 0; j < e.Unique; j++ {
+		_, err := in.TrySave("value-" + strconv.Itoa(1000+j))
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, cap, in.Cap())
+}
+
+func TestEstimateCapacityEmpty(t *testing.T) {
+	assert.Equal(t, intern.Estimate{Cap: 16}, intern.EstimateCapacity(nil, 1000))
+}
@@ -0,0 +1,199 @@
+package intern
+
+import (
+	"cmp"
+	"slices"
+)
+
+// EvictionPolicy chooses which strings Evict drops
+type EvictionPolicy int
+
+const (
+	// EvictOldest drops the strings that were saved first, or rather those with the
+	// lowest offsets, which differ only once StartCompaction has moved strings. It needs
+	// no record of how strings are used, and is what Evict does unless another policy is
+	// chosen
+	EvictOldest EvictionPolicy = iota
+	// EvictLFU drops the strings that are used least often, so a string that is seen now
+	// and again outlives a burst of strings that are each seen once. This suits skewed
+	// data such as telemetry labels, where a few values account for most of the traffic.
+	// Each string has an 8-bit logarithmic counter of how often it has been saved or
+	// looked up, which halves each time Evict runs so that strings that were popular
+	// long ago eventually make way.
+	EvictLFU
+	// EvictClock approximates dropping the least recently used strings with the clock, or
+	// second chance, algorithm. Each string has a bit that is set whenever it is saved or
+	// looked up. Evict sweeps through the strings in offset order, carrying on from where
+	// it last stopped: a string whose bit is set has the bit cleared and is passed over,
+	// and one whose bit is clear is dropped. This needs no lists linking the strings in
+	// order of use, so tracking recency costs a single byte per string.
+	EvictClock
+)
+
+const (
+	// lfuInit is the counter a new string starts with. Starting above zero gives new
+	// strings a chance to be used again before they are evicted, and leaves room for
+	// the counters of strings seen only once to decay below those of new ones
+	lfuInit = 5
+	// lfuLogFactor controls how quickly the counters saturate. Reaching a counter of
+	// lfuInit + n takes about lfuLogFactor * n * n / 2 uses
+	lfuLogFactor = 10
+)
+
+// eviction holds the state needed to choose strings to evict
+type eviction struct {
+	policy EvictionPolicy
+	// rng drives the probabilistic counter increments
+	rng uint64
+	// hand is the offset at which the clock sweep carries on
+	hand int
+}
+
+// WithEviction chooses the policy Evict uses to pick strings to drop. Policies other than
+// EvictOldest record how each string is used in a byte stored alongside it, and update it
+// each time the string is saved or looked up. That is the only memory they add.
+func WithEviction(policy EvictionPolicy) Option {
+	return func(i *Intern) {
+		i.evict.policy = policy
+		i.arena.meta = policy != EvictOldest
+	}
+}
+
+// touch records a use of the string at offset
+func (i *Intern) touch(offset int) {
+	switch i.evict.policy {
+	case EvictLFU:
+		c := i.arena.metaByte(offset)
+		if *c == 255 {
+			return
+		}
+		// The chance of counting a use falls as the counter rises, so that 8 bits
+		// cover a wide range of frequencies
+		p := uint64(max(int(*c)-lfuInit, 0)*lfuLogFactor + 1)
+		if i.random()%p == 0 {
+			*c++
+		}
+	case EvictClock:
+		// Only write when the bit changes, to avoid dirtying memory on every use
+		if c := i.arena.metaByte(offset); *c == 0 {
+			*c = 1
+		}
+	}
+}
+
+// inserted sets up the usage record of a newly stored string
+func (i *Intern) inserted(offset int) {
+	switch i.evict.policy {
+	case EvictLFU:
+		*i.arena.metaByte(offset) = lfuInit
+	case EvictClock:
+		*i.arena.metaByte(offset) = 1
+	}
+}
+
+// random returns a pseudo-random number, using xorshift
+func (i *Intern) random() uint64 {
+	x := i.evict.rng
+	if x == 0 {
+		x = i.seed | 1
+	}
+	x ^= x << 13
+	x ^= x >> 7
+	x ^= x << 17
+	i.evict.rng = x
+	return x
+}
+
+// Evict drops n strings chosen by the policy set with WithEviction, or all of them if
+// there are fewer than n, and reclaims the space they took as Collect does. Pinned
+// strings are never dropped and don't count towards n. Like Collect it moves every
+// string that is kept, and returns a map from each one's old offset to its new one.
+func (i *Intern) Evict(n int) map[int]int {
+	var candidates []slot
+	i.eachSlot(func(s slot) {
+		if !i.pinned.Has(s.index - 1) {
+			candidates = append(candidates, s)
+		}
+	})
+
+	// Order the candidates with the strings to evict first, breaking ties by dropping
+	// older strings first. The clock sweeps through them in offset order
+	switch i.evict.policy {
+	case EvictLFU:
+		slices.SortFunc(candidates, func(a, b slot) int {
+			return cmp.Or(
+				cmp.Compare(*i.arena.metaByte(a.index - 1), *i.arena.metaByte(b.index - 1)),
+				cmp.Compare(a.index, b.index),
+			)
+		})
+	default:
+		slices.SortFunc(candidates, func(a, b slot) int {
+			return cmp.Compare(a.index, b.index)
+		})
+	}
+
+	var drop Set
+	if i.evict.policy == EvictClock {
+		drop = i.sweep(candidates, n)
+	} else {
+		for _, s := range candidates[:min(n, len(candidates))] {
+			drop.Add(s.index - 1)
+		}
+	}
+	remap := i.rebuild(func(offset int) bool {
+		return !drop.Has(offset)
+	})
+
+	if i.evict.policy == EvictLFU {
+		for _, offset := range remap {
+			if c := i.arena.metaByte(offset); *c > lfuInit {
+				*c -= (*c - lfuInit) / 2
+			}
+		}
+	}
+	return remap
+}
+
+// moveHand moves the clock hand to the new offset of the first string kept at or after
+// it, once the strings have been moved as described by remap
+func (i *Intern) moveHand(remap map[int]int) {
+	next := -1
+	for old, offset := range remap {
+		if old >= i.evict.hand && (next == -1 || offset < next) {
+			next = offset
+		}
+	}
+	i.evict.hand = max(next, 0)
+}
+
+// sweep runs the clock over candidates, which are in offset order, until it has chosen n
+// strings to drop or has dropped them all. It leaves the hand at the offset after the last
+// string it looked at.
+func (i *Intern) sweep(candidates []slot, n int) Set {
+	var drop Set
+	if len(candidates) == 0 || n <= 0 {
+		return drop
+	}
+	k, _ := slices.BinarySearchFunc(candidates, i.evict.hand+1, func(s slot, index int) int {
+		return cmp.Compare(s.index, index)
+	})
+	// Two passes are enough: the first clears every bit it doesn't act on
+	for dropped, seen := 0, 0; dropped < n && seen < 2*len(candidates); seen++ {
+		if k == len(candidates) {
+			k = 0
+		}
+		offset := candidates[k].index - 1
+		if c := i.arena.metaByte(offset); *c != 0 {
+			*c = 0
+		} else if !drop.Has(offset) {
+			drop.Add(offset)
+			dropped++
+		}
+		k++
+	}
+	if k == len(candidates) {
+		k = 0
+	}
+	i.evict.hand = candidates[k].index - 1
+	return drop
+}
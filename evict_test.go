@@ -0,0 +1,153 @@
+package intern_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvictOldest(t *testing.T) {
+	var in intern.Intern
+	for j := range 10 {
+		in.Save("value-" + strconv.Itoa(j))
+	}
+	pinned := in.Save("value-0")
+	in.Pin(pinned)
+
+	remap := in.Evict(3)
+	assert.Len(t, remap, 7)
+	for j := range 10 {
+		_, ok := in.Lookup("value-" + strconv.Itoa(j))
+		assert.Equal(t, j == 0 || j > 3, ok, j)
+	}
+
+	in.Evict(100)
+	assert.Equal(t, 1, in.Len())
+	_, ok := in.Lookup("value-0")
+	assert.True(t, ok)
+}
+
+func TestEvictLFU(t *testing.T) {
+	in := intern.New(16, intern.WithEviction(intern.EvictLFU))
+	common := []string{"GET", "POST", "200", "404"}
+	for range 100 {
+		for _, val := range common {
+			in.Save(val)
+		}
+	}
+	// A burst of strings each seen once, after the common ones
+	for j := range 20 {
+		in.Save("request-" + strconv.Itoa(j))
+	}
+
+	in.Evict(20)
+	assert.Equal(t, len(common), in.Len())
+	for _, val := range common {
+		offset, ok := in.Lookup(val)
+		assert.True(t, ok)
+		assert.Equal(t, val, in.Get(offset))
+	}
+}
+
+func TestEvictLFUNewBeatsStale(t *testing.T) {
+	in := intern.New(16, intern.WithEviction(intern.EvictLFU))
+	in.Save("once")
+	in.Save("twice")
+	in.Save("twice")
+
+	// Evicting nothing still decays the counters. A string seen only once never sinks
+	// below a new one, but ties go against the older string
+	in.Evict(0)
+	in.Save("new")
+	in.Evict(1)
+	_, ok := in.Lookup("once")
+	assert.False(t, ok)
+	_, ok = in.Lookup("new")
+	assert.True(t, ok)
+	_, ok = in.Lookup("twice")
+	assert.True(t, ok)
+}
+
+func TestEvictLFUPinned(t *testing.T) {
+	in := intern.New(16, intern.WithEviction(intern.EvictLFU))
+	in.Pin(in.Save("rare"))
+	for range 10 {
+		in.Save("common")
+	}
+	in.Evict(1)
+	assert.Equal(t, 1, in.Len())
+	_, ok := in.Lookup("rare")
+	assert.True(t, ok)
+}
+
+func TestEvictCollectKeepsCounts(t *testing.T) {
+	in := intern.New(16, intern.WithEviction(intern.EvictLFU))
+	var live intern.Set
+	for range 10 {
+		live.Add(in.Save("common"))
+	}
+	live.Add(in.Save("rare"))
+	in.Save("dead")
+
+	in.Collect(live.All())
+	assert.Equal(t, 2, in.Len())
+	in.Evict(1)
+	_, ok := in.Lookup("common")
+	assert.True(t, ok)
+}
+
+func TestEvictClock(t *testing.T) {
+	in := intern.New(16, intern.WithEviction(intern.EvictClock))
+	for j := range 6 {
+		in.Save("value-" + strconv.Itoa(j))
+	}
+
+	// Every string has just been used, so the first sweep clears all the bits and the
+	// second drops the oldest
+	in.Evict(1)
+	assert.Equal(t, 5, in.Len())
+	_, ok := in.Lookup("value-0")
+	assert.False(t, ok)
+
+	// value-2 gets a second chance. The hand carries on from value-1
+	in.Lookup("value-2")
+	in.Evict(2)
+	for j, want := range []bool{false, false, true, false, true, true} {
+		_, ok := in.Lookup("value-" + strconv.Itoa(j))
+		assert.Equal(t, want, ok, j)
+	}
+}
+
+func TestEvictClockWraps(t *testing.T) {
+	in := intern.New(16, intern.WithEviction(intern.EvictClock))
+	for j := range 4 {
+		in.Save("value-" + strconv.Itoa(j))
+	}
+	in.Evict(2)
+	// The hand is now at value-2, which gets a second chance
+	in.Save("value-2")
+	in.Evict(1)
+	_, ok := in.Lookup("value-3")
+	assert.False(t, ok)
+	assert.Equal(t, 1, in.Len())
+
+	// The hand goes back round to value-2, which has used up its second chance
+	in.Evict(1)
+	assert.Equal(t, 0, in.Len())
+
+	in.Evict(1)
+	in.Save("new")
+	assert.Equal(t, 1, in.Len())
+}
+
+func TestEvictClockPinned(t *testing.T) {
+	in := intern.New(16, intern.WithEviction(intern.EvictClock))
+	in.Pin(in.Save("pinned"))
+	in.Save("other")
+	in.Evict(5)
+	assert.Equal(t, 1, in.Len())
+	_, ok := in.Lookup("pinned")
+	assert.True(t, ok)
+}
@@ -0,0 +1,72 @@
+package intern
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteSnapshotFile writes the data written by WriteSnapshot to the named file, creating
+// it with permissions perm if it doesn't exist. The data goes to a temporary file in the
+// same directory, which is flushed to disk and then renamed over name. Readers therefore
+// see either the old file or the complete new one, never a mixture, even if the process
+// is killed or the machine loses power part way through. A temporary file left behind by
+// a write that didn't finish has a name starting with name + ".tmp" and can be deleted.
+func (i *Intern) WriteSnapshotFile(name string, perm os.FileMode) (err error) {
+	defer traceRegion("intern.writeSnapshotFile").End()
+	dir, base := filepath.Split(name)
+	f, err := os.CreateTemp(dir, base+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if _, err := i.WriteSnapshot(f); err != nil {
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		return err
+	}
+	syncDir(dir)
+	return nil
+}
+
+// syncDir flushes dir to disk, so that a file renamed into it survives a crash. This is
+// best effort: not every platform can sync a directory, and by this point the file itself
+// is safely on disk.
+func syncDir(dir string) {
+	if dir == "" {
+		dir = "."
+	}
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// ReadSnapshotFile saves each string in the named file, which should have been written
+// by WriteTo, WriteSnapshot, WriteCompressed or WriteSnapshotFile. A file that has been
+// cut short gives an error wrapping ErrFormat, though the strings before the point where
+// it was cut will have been saved.
+func (i *Intern) ReadSnapshotFile(name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = i.ReadFrom(f)
+	return err
+}
@@ -0,0 +1,99 @@
+package intern_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshotFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "dict")
+
+	var in intern.Intern
+	for j := range 100 {
+		in.Save("value-" + strconv.Itoa(j))
+	}
+	assert.NoError(t, in.WriteSnapshotFile(name, 0o644))
+
+	fi, err := os.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o644), fi.Mode().Perm())
+
+	var out intern.Intern
+	assert.NoError(t, out.ReadSnapshotFile(name))
+	assert.Equal(t, 100, out.Len())
+	for _, val := range in.All() {
+		_, ok := out.Lookup(val)
+		assert.True(t, ok)
+	}
+
+	// Overwriting replaces the file and leaves nothing else behind
+	in.Save("another")
+	assert.NoError(t, in.WriteSnapshotFile(name, 0o644))
+	entries, err := os.ReadDir(filepath.Dir(name))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	out = intern.Intern{}
+	assert.NoError(t, out.ReadSnapshotFile(name))
+	assert.Equal(t, 101, out.Len())
+}
+
+func TestSnapshotFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	var in intern.Intern
+	in.Save("hat")
+
+	err := in.WriteSnapshotFile(filepath.Join(dir, "missing", "dict"), 0o644)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	err = in.ReadSnapshotFile(filepath.Join(dir, "dict"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestSnapshotFileTruncated(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "dict")
+
+	var in intern.Intern
+	for j := range 20 {
+		in.Save("value-" + strconv.Itoa(j))
+	}
+	assert.NoError(t, in.WriteSnapshotFile(name, 0o644))
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+
+	// A writer killed part way through leaves a partial temporary file. The snapshot
+	// itself is untouched
+	assert.NoError(t, os.WriteFile(name+".tmp123", data[:len(data)/2], 0o644))
+	var out intern.Intern
+	assert.NoError(t, out.ReadSnapshotFile(name))
+	assert.Equal(t, 20, out.Len())
+
+	// Data cut off at any point is detected
+	for l := range len(data) {
+		assert.NoError(t, os.WriteFile(name, data[:l], 0o644))
+		var out intern.Intern
+		err := out.ReadSnapshotFile(name)
+		if !assert.True(t, errors.Is(err, intern.ErrFormat), "length %d", l) {
+			break
+		}
+	}
+}
+
+func TestSnapshotFileHugeLength(t *testing.T) {
+	// A file whose header claims far more data than it holds gives an error rather than
+	// an attempt to allocate all of it
+	name := filepath.Join(t.TempDir(), "dict")
+	for _, flags := range []uint32{0, 2} {
+		assert.NoError(t, os.WriteFile(name, forgedFormat(flags, 1, 1<<38, 0, 1<<38), 0o644))
+		var out intern.Intern
+		err := out.ReadSnapshotFile(name)
+		assert.EqualError(t, err, "intern: invalid dictionary format: data is truncated")
+		assert.Equal(t, 0, out.Len())
+	}
+}
@@ -0,0 +1,106 @@
+package intern
+
+import (
+	"maps"
+	"unicode"
+	"unicode/utf8"
+)
+
+// foldIndex finds stored strings regardless of case. It maps the case-folded form of
+// each string to the first string saved with that form. It holds strings rather than
+// offsets so that it stays right when compaction moves strings.
+type foldIndex struct {
+	keys map[string]string
+	// buf is reused to build folded keys
+	buf []byte
+}
+
+// WithFoldIndex keeps a secondary index of the stored strings by their case-folded
+// form, so that LookupFold can find "Content-Type" given "content-type". The strings
+// themselves are stored as they are given. The index costs a map entry per distinct
+// folded form, plus a copy of the folded form for each string that isn't already in it.
+func WithFoldIndex() Option {
+	return func(i *Intern) {
+		i.fold = &foldIndex{keys: make(map[string]string)}
+	}
+}
+
+// LookupFold looks for a stored string equal to val under Unicode case folding, as
+// strings.EqualFold compares them, without storing anything. If several stored strings
+// match, it returns the first one saved. It returns the string's offset and true if
+// there is a match, or InvalidOffset and false if not. Without WithFoldIndex it only
+// finds exact matches, like Lookup.
+func (i *Intern) LookupFold(val string) (offset int, ok bool) {
+	if i.fold == nil {
+		return i.Lookup(val)
+	}
+	f := i.fold
+	f.buf = appendFolded(f.buf[:0], val)
+	orig, ok := f.keys[string(f.buf)]
+	if !ok {
+		return InvalidOffset, false
+	}
+	return i.Lookup(orig)
+}
+
+// add indexes val, which is a stored string
+func (f *foldIndex) add(val string) {
+	f.buf = appendFolded(f.buf[:0], val)
+	if _, ok := f.keys[string(f.buf)]; ok {
+		return
+	}
+	key := val
+	if string(f.buf) != val {
+		key = string(f.buf)
+	}
+	f.keys[key] = val
+}
+
+// clone returns a copy of the index that can be changed independently
+func (f *foldIndex) clone() *foldIndex {
+	return &foldIndex{keys: maps.Clone(f.keys)}
+}
+
+// rebuildFold indexes the stored strings afresh, once strings have been dropped
+func (i *Intern) rebuildFold() {
+	f := &foldIndex{keys: make(map[string]string, len(i.fold.keys)), buf: i.fold.buf}
+	for _, val := range i.AllInOrder() {
+		f.add(val)
+	}
+	i.fold = f
+}
+
+// appendFolded appends the case-folded form of val to dst. Each character is replaced
+// by the smallest character it is equal to under simple case folding, so two strings
+// fold to the same bytes exactly when strings.EqualFold considers them equal. Invalid
+// UTF-8 is copied as it is.
+func appendFolded(dst []byte, val string) []byte {
+	for j := 0; j < len(val); {
+		c := val[j]
+		if c < utf8.RuneSelf {
+			if 'a' <= c && c <= 'z' {
+				c -= 'a' - 'A'
+			}
+			dst = append(dst, c)
+			j++
+			continue
+		}
+		r, n := utf8.DecodeRuneInString(val[j:])
+		if r == utf8.RuneError && n == 1 {
+			dst = append(dst, c)
+		} else {
+			dst = utf8.AppendRune(dst, foldRune(r))
+		}
+		j += n
+	}
+	return dst
+}
+
+// foldRune returns the smallest character in r's case folding orbit
+func foldRune(r rune) rune {
+	m := r
+	for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+		m = min(m, f)
+	}
+	return m
+}
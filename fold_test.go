@@ -0,0 +1,74 @@
+package intern_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupFold(t *testing.T) {
+	in := intern.New(16, intern.WithFoldIndex())
+	ct := in.Save("Content-Type")
+	in.Save("CONTENT-TYPE")
+	straße := in.Save("Straße")
+	kelvin := in.Save("K")
+
+	tests := []struct {
+		val    string
+		offset int
+	}{
+		{val: "content-type", offset: ct},
+		{val: "CONTENT-TYPE", offset: ct},
+		{val: "Content-Type", offset: ct},
+		{val: "STRAßE", offset: straße},
+		{val: "k", offset: kelvin},
+	}
+	for _, test := range tests {
+		t.Run(test.val, func(t *testing.T) {
+			offset, ok := in.LookupFold(test.val)
+			assert.True(t, ok)
+			assert.Equal(t, test.offset, offset)
+		})
+	}
+
+	offset, ok := in.LookupFold("content-length")
+	assert.False(t, ok)
+	assert.Equal(t, intern.InvalidOffset, offset)
+	// What's stored is unchanged
+	assert.Equal(t, "Content-Type", in.Get(ct))
+	assert.Equal(t, 4, in.Len())
+}
+
+func TestLookupFoldNoIndex(t *testing.T) {
+	in := intern.New(16)
+	hat := in.Save("Hat")
+	offset, ok := in.LookupFold("Hat")
+	assert.True(t, ok)
+	assert.Equal(t, hat, offset)
+	_, ok = in.LookupFold("hat")
+	assert.False(t, ok)
+}
+
+func TestLookupFoldCollect(t *testing.T) {
+	in := intern.New(16, intern.WithFoldIndex())
+	in.Save("Hat")
+	second := in.Save("HAT")
+
+	remap := in.Collect(slices.Values([]int{second}))
+	offset, ok := in.LookupFold("hat")
+	assert.True(t, ok)
+	assert.Equal(t, remap[second], offset)
+	assert.Equal(t, "HAT", in.Get(offset))
+
+	// Compaction moves the strings, and drops those that aren't live
+	in.StartCompaction(slices.Values([]int{in.Save("cat")}), nil)
+	for !in.CompactStep(16) {
+	}
+	_, ok = in.LookupFold("HAT")
+	assert.False(t, ok)
+	offset, ok = in.LookupFold("CAT")
+	assert.True(t, ok)
+	assert.Equal(t, "cat", in.Get(offset))
+}
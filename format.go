@@ -0,0 +1,342 @@
+package intern
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+)
+
+// WriteTo and ReadFrom use a flat binary layout that is simple to read from other
+// languages. All integers are little-endian.
+//
+//	offset  size  field
+//	0       4     magic "ISTR"
+//	4       4     version, currently 1
+//	8       4     flags. Readers should reject flags they don't understand
+//	12      4     t, the number of slots in the lookup table, or 0 if there is no table
+//	16      8     n, the number of strings
+//	24      8     m, the total length of the strings in bytes
+//	32      8t    lookup table, present if flag 0x1 is set. See below
+//	32+8t   8n+8  offsets table: n+1 uint64 values. String k occupies bytes
+//	              offsets[k] to offsets[k+1] of the strings section. offsets[0] is 0 and
+//	              offsets[n] is m
+//	40+8t+8n m    strings section: the strings, one after another, with no separators
+//
+// If flag 0x2 is set, everything after the header is compressed as a single gzip member
+// (RFC 1952), written by WriteCompressed. The layout of the data once decompressed is
+// unchanged.
+//
+// Strings are written in offset order, as AllInOrder gives them, so their position in the
+// file is a dense ID from 0 to n-1. That is the order they were saved unless a compaction
+// has moved them.
+//
+// The lookup table, written by WriteSnapshot, lets a string be found without loading
+// everything into memory. It is an open-addressed hash table of t slots, t a power of two,
+// keyed by the 64-bit xxHash (XXH64) of each string with seed 0. Each slot is two uint32
+// values: the top 32 bits of the hash, then the string's position + 1, or 0 if the slot is
+// empty. A string with hash h lives somewhere in the run of non-empty slots that starts
+// at slot h mod t. To find it, start there and step forward a slot at a time, wrapping
+// around at the end of the table, until reaching the string's slot, or an empty slot,
+// which means the string isn't present. The table always has at least one empty slot.
+// It comes straight after the header so that everything a lookup needs except the string
+// itself sits together at the front of the file.
+const (
+	formatMagic      = "ISTR"
+	formatVersion    = 1
+	formatHeaderSize = 32
+	// formatFlagTable marks data that includes a lookup table
+	formatFlagTable = 0x1
+	// formatFlagGzip marks data compressed with gzip after the header
+	formatFlagGzip = 0x2
+	// formatSlotSize is the size of each slot in the lookup table
+	formatSlotSize = 8
+	// formatMaxTableStrings is the most strings a lookup table can index. The table has at
+	// least twice as many slots as strings, and the number of slots must fit in 32 bits
+	formatMaxTableStrings = 1 << 30
+)
+
+// ErrFormat is returned when reading data that isn't in the format written by WriteTo
+var ErrFormat = errors.New("intern: invalid dictionary format")
+
+// WriteTo writes every stored string to w in the binary format described above, in the
+// order AllInOrder gives them. It implements io.WriterTo.
+func (i *Intern) WriteTo(w io.Writer) (int64, error) {
+	return i.writeFormat(w, 0, 0)
+}
+
+// WriteSnapshot is like WriteTo, but includes the lookup table described above, which
+// takes between 16 and 32 bytes per string. The table and the offsets come before the
+// strings, so a lookup made directly on the data, for instance after mapping it into
+// memory, touches only a handful of pages, and the pages holding hot metadata stay
+// resident together. ReadFrom skips the table.
+func (i *Intern) WriteSnapshot(w io.Writer) (int64, error) {
+	return i.writeFormat(w, formatFlagTable, 0)
+}
+
+// WriteCompressed is like WriteTo, but compresses everything after the header with gzip
+// at the given level, as in compress/gzip. Dictionaries typically shrink to a fifth or a
+// tenth of their size. ReadFrom and ReadSnapshotFile decompress the data as they read it,
+// but it can't be used in place.
+func (i *Intern) WriteCompressed(w io.Writer, level int) (int64, error) {
+	return i.writeFormat(w, formatFlagGzip, level)
+}
+
+// writeFormat writes the binary format with the given flags. level is the gzip
+// compression level, if the data is compressed
+func (i *Intern) writeFormat(w io.Writer, flags uint32, level int) (int64, error) {
+	defer traceRegion("intern.write").End()
+	offsets := i.offsets()
+	slices.Sort(offsets)
+
+	var total uint64
+	for _, offset := range offsets {
+		total += uint64(len(i.Get(offset)))
+	}
+
+	var lookup []byte
+	if flags&formatFlagTable != 0 {
+		if len(offsets) > formatMaxTableStrings {
+			return 0, fmt.Errorf("%w: a lookup table can index at most %d strings", ErrTooLarge, formatMaxTableStrings)
+		}
+		lookup = i.formatTable(offsets)
+	}
+
+	cw := &countingWriter{w: w}
+	var body io.Writer = cw
+	var gz *gzip.Writer
+	if flags&formatFlagGzip != 0 {
+		var err error
+		if gz, err = gzip.NewWriterLevel(cw, level); err != nil {
+			return 0, err
+		}
+		body = gz
+	}
+	var buf [formatHeaderSize]byte
+	copy(buf[:], formatMagic)
+	binary.LittleEndian.PutUint32(buf[4:], formatVersion)
+	binary.LittleEndian.PutUint32(buf[8:], flags)
+	binary.LittleEndian.PutUint32(buf[12:], uint32(len(lookup)/formatSlotSize))
+	binary.LittleEndian.PutUint64(buf[16:], uint64(len(offsets)))
+	binary.LittleEndian.PutUint64(buf[24:], total)
+	if _, err := cw.Write(buf[:]); err != nil {
+		return cw.n, err
+	}
+
+	bw := bufio.NewWriterSize(body, 64*1024)
+	bw.Write(lookup)
+
+	var pos uint64
+	bw.Write(binary.LittleEndian.AppendUint64(buf[:0], pos))
+	for _, offset := range offsets {
+		pos += uint64(len(i.Get(offset)))
+		bw.Write(binary.LittleEndian.AppendUint64(buf[:0], pos))
+	}
+	for _, offset := range offsets {
+		bw.WriteString(i.Get(offset))
+	}
+
+	// bufio.Writer remembers the first error, so it is enough to check it here
+	if err := bw.Flush(); err != nil {
+		return cw.n, err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return cw.n, err
+		}
+	}
+	return cw.n, nil
+}
+
+// formatTable builds the lookup table for the strings at offsets
+func (i *Intern) formatTable(offsets []int) []byte {
+	return buildFormatTable(len(offsets), func(k int) string {
+		return i.Get(offsets[k])
+	})
+}
+
+// buildFormatTable builds the lookup table for n strings, where get returns string k.
+// The table is kept at most half full so that probe sequences are short.
+func buildFormatTable(n int, get func(k int) string) []byte {
+	slots := 8
+	for slots < 2*n {
+		slots *= 2
+	}
+	lookup := make([]byte, slots*formatSlotSize)
+	mask := uint64(slots - 1)
+	for k := range n {
+		h := XXHash64(get(k), 0)
+		for pos := h & mask; ; pos = (pos + 1) & mask {
+			slot := lookup[pos*formatSlotSize:]
+			if binary.LittleEndian.Uint32(slot[4:]) == 0 {
+				binary.LittleEndian.PutUint32(slot, uint32(h>>32))
+				binary.LittleEndian.PutUint32(slot[4:], uint32(k+1))
+				break
+			}
+		}
+	}
+	return lookup
+}
+
+// countingWriter counts the bytes written to w
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ReadFrom saves each string in data written by WriteTo, WriteSnapshot or
+// WriteCompressed, in the order they were written. Reading into an empty Intern made
+// with the same options, WithChunkSize and WithEviction among them, gives the strings
+// back the offsets they had, unless StartCompaction had moved them. Otherwise the
+// offsets differ, and strings must be looked up again. It reads exactly the bytes that
+// were written and no more, so the data may be followed by something else. The one
+// exception is compressed data read from an r that isn't an io.ByteReader, which is read
+// through a buffer. ReadFrom implements io.ReaderFrom.
+func (i *Intern) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	err := i.readFormat(cr)
+	return cr.n, err
+}
+
+// readFormat reads the binary format from r
+func (i *Intern) readFormat(r *countingReader) error {
+	var hdr [formatHeaderSize]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return formatError(err)
+	}
+	if string(hdr[:4]) != formatMagic {
+		return fmt.Errorf("%w: bad magic number", ErrFormat)
+	}
+	if v := binary.LittleEndian.Uint32(hdr[4:]); v != formatVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrFormat, v)
+	}
+	flags := binary.LittleEndian.Uint32(hdr[8:])
+	if flags&^(formatFlagTable|formatFlagGzip) != 0 {
+		return fmt.Errorf("%w: unsupported flags %#x", ErrFormat, flags)
+	}
+	slots := binary.LittleEndian.Uint32(hdr[12:])
+	count := binary.LittleEndian.Uint64(hdr[16:])
+	total := binary.LittleEndian.Uint64(hdr[24:])
+	if count >= math.MaxInt64/8 || total > math.MaxInt64 {
+		return fmt.Errorf("%w: %d strings of %d bytes is too many", ErrFormat, count, total)
+	}
+
+	var body io.Reader = r
+	var gz *gzip.Reader
+	if flags&formatFlagGzip != 0 {
+		// The decompressor reads a byte at a time from an io.ByteReader, so it doesn't
+		// read past the end of the compressed data. Anything else it would wrap in a
+		// buffer anyway
+		r.br, _ = r.r.(io.ByteReader)
+		if r.br == nil {
+			b := bufio.NewReader(r.r)
+			r.r, r.br = b, b
+		}
+		var err error
+		if gz, err = gzip.NewReader(r); err != nil {
+			return formatError(err)
+		}
+		gz.Multistream(false)
+		body = gz
+		defer gz.Close()
+	}
+
+	if flags&formatFlagTable != 0 {
+		// The lookup table is only needed when reading the data in place
+		if _, err := io.CopyN(io.Discard, body, int64(slots)*formatSlotSize); err != nil {
+			return formatError(err)
+		}
+	}
+
+	// The counts haven't been checked against the data yet, so the offsets are
+	// gathered as they arrive rather than allocated up front
+	br := bufio.NewReaderSize(io.LimitReader(body, int64(8*(count+1))), 64*1024)
+	offsets := make([]uint64, 0, min(count+1, 64*1024))
+	var buf [8]byte
+	for j := uint64(0); j <= count; j++ {
+		if _, err := io.ReadFull(br, buf[:]); err != nil {
+			return formatError(err)
+		}
+		offset := binary.LittleEndian.Uint64(buf[:])
+		if (j == 0 && offset != 0) || (j > 0 && offset < offsets[j-1]) || offset > total {
+			return fmt.Errorf("%w: bad offset %d for string %d", ErrFormat, offset, j)
+		}
+		offsets = append(offsets, offset)
+	}
+	if offsets[count] != total {
+		return fmt.Errorf("%w: strings end at %d, not %d", ErrFormat, offsets[count], total)
+	}
+
+	// The lengths are no more trustworthy than the counts, so each string is copied into
+	// a buffer that only grows as its bytes arrive
+	br = bufio.NewReaderSize(io.LimitReader(body, int64(total)), 64*1024)
+	var val bytes.Buffer
+	for j := range count {
+		val.Reset()
+		if _, err := io.CopyN(&val, br, int64(offsets[j+1]-offsets[j])); err != nil {
+			return formatError(err)
+		}
+		if _, err := i.saveBytes(val.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	if gz != nil {
+		// Reading to the end checks the gzip trailer
+		if n, err := io.Copy(io.Discard, gz); err != nil || n != 0 {
+			return formatError(cmp.Or(err, errTrailing))
+		}
+	}
+	return nil
+}
+
+// errTrailing is reported when compressed data holds more than the header describes
+var errTrailing = fmt.Errorf("%w: unexpected data after the strings", ErrFormat)
+
+// formatError reports running out of data part way through, or data the decompressor
+// can't make sense of, as a format error
+func formatError(err error) error {
+	var corrupt flate.CorruptInputError
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		return fmt.Errorf("%w: data is truncated", ErrFormat)
+	case errors.As(err, &corrupt) || err == gzip.ErrHeader || err == gzip.ErrChecksum:
+		return fmt.Errorf("%w: %w", ErrFormat, err)
+	}
+	return err
+}
+
+// countingReader counts the bytes read from r. br is set when the data is read through a
+// decompressor, and must read from the same source as r
+type countingReader struct {
+	r  io.Reader
+	br io.ByteReader
+	n  int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.br.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
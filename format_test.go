@@ -0,0 +1,289 @@
+package intern_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteTo(t *testing.T) {
+	var in intern.Intern
+	in.Save("hat")
+	in.Save("")
+	in.Save("scarf")
+
+	var b bytes.Buffer
+	n, err := in.WriteTo(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(b.Len()), n)
+
+	le := binary.LittleEndian
+	data := b.Bytes()
+	assert.Equal(t, "ISTR", string(data[:4]))
+	assert.Equal(t, uint32(1), le.Uint32(data[4:]))
+	assert.Equal(t, uint32(0), le.Uint32(data[8:]))
+	assert.Equal(t, uint64(3), le.Uint64(data[16:]))
+	assert.Equal(t, uint64(8), le.Uint64(data[24:]))
+	var offsets []uint64
+	for j := range 4 {
+		offsets = append(offsets, le.Uint64(data[32+8*j:]))
+	}
+	assert.Equal(t, []uint64{0, 3, 3, 8}, offsets)
+	assert.Equal(t, "hatscarf", string(data[64:]))
+}
+
+func TestWriteToError(t *testing.T) {
+	var in intern.Intern
+	in.Save("hat")
+	_, err := in.WriteTo(errWriter{})
+	assert.EqualError(t, err, "oops")
+}
+
+func TestReadFrom(t *testing.T) {
+	var in intern.Intern
+	vals := []string{"hat", "", "scarf", strings.Repeat("long", 50000), "gloves"}
+	offsets := make([]int, len(vals))
+	for j, val := range vals {
+		offsets[j] = in.Save(val)
+	}
+
+	var b bytes.Buffer
+	_, err := in.WriteTo(&b)
+	assert.NoError(t, err)
+	size := b.Len()
+	b.WriteString("trailing")
+
+	var out intern.Intern
+	n, err := out.ReadFrom(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(size), n)
+	assert.Equal(t, "trailing", b.String())
+	assert.Equal(t, len(vals), out.Len())
+	for j, val := range vals {
+		assert.Equal(t, offsets[j], out.Save(val))
+	}
+}
+
+func TestReadFromInvalid(t *testing.T) {
+	var in intern.Intern
+	in.Save("hat")
+	in.Save("scarf")
+	var b bytes.Buffer
+	_, err := in.WriteTo(&b)
+	assert.NoError(t, err)
+	good := b.Bytes()
+
+	corrupt := func(f func(data []byte)) []byte {
+		data := bytes.Clone(good)
+		f(data)
+		return data
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"empty", nil, "intern: invalid dictionary format: data is truncated"},
+		{"magic", corrupt(func(d []byte) { d[0] = 'X' }), "intern: invalid dictionary format: bad magic number"},
+		{"version", corrupt(func(d []byte) { d[4] = 2 }), "intern: invalid dictionary format: unsupported version 2"},
+		{"flags", corrupt(func(d []byte) { d[8] = 6 }), "intern: invalid dictionary format: unsupported flags 0x6"},
+		{"offsets", good[:40], "intern: invalid dictionary format: data is truncated"},
+		{"strings", good[:len(good)-1], "intern: invalid dictionary format: data is truncated"},
+		{"first offset", corrupt(func(d []byte) { d[32] = 1 }), "intern: invalid dictionary format: bad offset 1 for string 0"},
+		{"decreasing", corrupt(func(d []byte) { d[40] = 9 }), "intern: invalid dictionary format: bad offset 9 for string 1"},
+		{"end", corrupt(func(d []byte) { d[48] = 7 }), "intern: invalid dictionary format: strings end at 7, not 8"},
+		{"huge count", corrupt(func(d []byte) { d[23] = 0x10 }), "intern: invalid dictionary format: 1152921504606846978 strings of 8 bytes is too many"},
+		{"huge total", forgedFormat(0, 1, 1<<63, 0, 1<<63), "intern: invalid dictionary format: 1 strings of 9223372036854775808 bytes is too many"},
+		{"huge length", forgedFormat(0, 1, 1<<38, 0, 1<<38), "intern: invalid dictionary format: data is truncated"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var out intern.Intern
+			_, err := out.ReadFrom(bytes.NewReader(test.data))
+			assert.EqualError(t, err, test.err)
+			assert.True(t, errors.Is(err, intern.ErrFormat))
+		})
+	}
+}
+
+// forgedFormat returns a header claiming count strings of total bytes, followed by the
+// given offsets and no strings. With formatFlagGzip the offsets are compressed.
+func forgedFormat(flags uint32, count, total uint64, offsets ...uint64) []byte {
+	data := []byte("ISTR")
+	data = binary.LittleEndian.AppendUint32(data, 1)
+	data = binary.LittleEndian.AppendUint32(data, flags)
+	data = binary.LittleEndian.AppendUint32(data, 0)
+	data = binary.LittleEndian.AppendUint64(data, count)
+	data = binary.LittleEndian.AppendUint64(data, total)
+	var body []byte
+	for _, offset := range offsets {
+		body = binary.LittleEndian.AppendUint64(body, offset)
+	}
+	if flags&2 == 0 {
+		return append(data, body...)
+	}
+	b := bytes.NewBuffer(data)
+	w := gzip.NewWriter(b)
+	w.Write(body)
+	w.Close()
+	return b.Bytes()
+}
+
+func TestReadFromMaxBytes(t *testing.T) {
+	var in intern.Intern
+	for _, val := range []string{"a string that is long enough", "another string that is long"} {
+		in.Save(val)
+	}
+	var b bytes.Buffer
+	_, err := in.WriteTo(&b)
+	assert.NoError(t, err)
+
+	out := intern.New(16, intern.WithMaxBytes(1))
+	_, err = out.ReadFrom(&b)
+	assert.Equal(t, intern.ErrMaxBytes, err)
+}
+
+func TestWriteSnapshot(t *testing.T) {
+	var in intern.Intern
+	vals := []string{"hat", "", "scarf", "gloves", "a rather longer string"}
+	for j := range 100 {
+		vals = append(vals, "value-"+strconv.Itoa(j))
+	}
+	for _, val := range vals {
+		in.Save(val)
+	}
+
+	var b bytes.Buffer
+	n, err := in.WriteSnapshot(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(b.Len()), n)
+
+	le := binary.LittleEndian
+	data := b.Bytes()
+	assert.Equal(t, uint32(1), le.Uint32(data[8:]))
+	slots := le.Uint32(data[12:])
+	assert.Equal(t, uint32(256), slots)
+
+	offsetsStart := 32 + 8*int(slots)
+	stringsStart := offsetsStart + 8*(len(vals)+1)
+	get := func(k uint32) string {
+		start := le.Uint64(data[offsetsStart+8*int(k):])
+		end := le.Uint64(data[offsetsStart+8*int(k+1):])
+		return string(data[stringsStart+int(start) : stringsStart+int(end)])
+	}
+
+	// Find each string by following the documented probe sequence
+	lookup := func(val string) (uint32, bool) {
+		h := intern.XXHash64(val, 0)
+		mask := uint64(slots - 1)
+		for pos := h & mask; ; pos = (pos + 1) & mask {
+			slot := data[32+8*pos:]
+			k := le.Uint32(slot[4:])
+			if k == 0 {
+				return 0, false
+			}
+			if le.Uint32(slot) == uint32(h>>32) && get(k-1) == val {
+				return k - 1, true
+			}
+		}
+	}
+	for j, val := range vals {
+		k, ok := lookup(val)
+		assert.True(t, ok, val)
+		assert.Equal(t, uint32(j), k)
+	}
+	_, ok := lookup("missing")
+	assert.False(t, ok)
+
+	var out intern.Intern
+	n, err = out.ReadFrom(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(data)), n)
+	assert.Equal(t, len(vals), out.Len())
+	for _, val := range vals {
+		assert.Equal(t, in.Save(val), out.Save(val))
+	}
+}
+
+func TestWriteCompressed(t *testing.T) {
+	var in intern.Intern
+	for j := range 1000 {
+		in.Save("https://example.com/some/path/" + strconv.Itoa(j))
+	}
+
+	var plain, compressed bytes.Buffer
+	_, err := in.WriteTo(&plain)
+	assert.NoError(t, err)
+	n, err := in.WriteCompressed(&compressed, gzip.BestCompression)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(compressed.Len()), n)
+	assert.True(t, compressed.Len()*5 < plain.Len())
+
+	// The header isn't compressed, and is the same apart from the flag
+	data := compressed.Bytes()
+	assert.Equal(t, uint32(2), binary.LittleEndian.Uint32(data[8:]))
+	assert.Equal(t, plain.Bytes()[16:32], data[16:32])
+
+	size := compressed.Len()
+	compressed.WriteString("trailing")
+	r := bytes.NewReader(compressed.Bytes())
+	var out intern.Intern
+	n, err = out.ReadFrom(r)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(size), n)
+	assert.Equal(t, int64(len("trailing")), int64(r.Len()))
+	assert.Equal(t, 1000, out.Len())
+	for _, val := range in.AllInOrder() {
+		offset, ok := out.Lookup(val)
+		assert.True(t, ok)
+		assert.Equal(t, in.Save(val), offset)
+	}
+}
+
+func TestWriteCompressedLevel(t *testing.T) {
+	var in intern.Intern
+	_, err := in.WriteCompressed(&bytes.Buffer{}, 42)
+	assert.Error(t, err)
+}
+
+func TestReadCompressedInvalid(t *testing.T) {
+	var in intern.Intern
+	for j := range 100 {
+		in.Save("value-" + strconv.Itoa(j))
+	}
+	var b bytes.Buffer
+	_, err := in.WriteCompressed(&b, gzip.DefaultCompression)
+	assert.NoError(t, err)
+	good := b.Bytes()
+
+	for _, data := range [][]byte{
+		good[:40],
+		good[:len(good)-1],
+		append(bytes.Clone(good[:len(good)-8]), 1, 2, 3, 4, 5, 6, 7, 8),
+	} {
+		var out intern.Intern
+		_, err := out.ReadFrom(bytes.NewReader(data))
+		assert.True(t, errors.Is(err, intern.ErrFormat), err)
+	}
+
+	// The header is not compressed, so its lengths are no more to be trusted
+	_, err = new(intern.Intern).ReadFrom(bytes.NewReader(forgedFormat(2, 1, 1<<38, 0, 1<<38)))
+	assert.EqualError(t, err, "intern: invalid dictionary format: data is truncated")
+
+	// A gzip stream holding more than the header describes
+	data := bytes.Clone(good)
+	binary.LittleEndian.PutUint64(data[16:], 10)
+	binary.LittleEndian.PutUint64(data[24:], 10*7)
+	var out intern.Intern
+	_, err = out.ReadFrom(bytes.NewReader(data))
+	assert.EqualError(t, err, "intern: invalid dictionary format: unexpected data after the strings")
+}
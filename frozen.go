@@ -0,0 +1,165 @@
+package intern
+
+import (
+	"encoding/binary"
+	"fmt"
+	"iter"
+	"math/bits"
+	"unsafe"
+)
+
+// Frozen is a read-only dictionary that reads strings directly from data in the format
+// written by WriteSnapshot, without copying them. It suits a vocabulary built into a
+// binary with go:embed, or a file mapped into memory. A Frozen is safe for concurrent
+// use.
+//
+// Strings are identified by their position in the data, from 0 to Len()-1, rather than
+// by the offsets the Intern that wrote them used.
+type Frozen struct {
+	count int
+	// mask is the number of slots in the lookup table - 1
+	mask    uint64
+	table   []byte
+	offsets []byte
+	strings []byte
+}
+
+// FromBytes opens data written by WriteSnapshot. The strings returned by the Frozen point
+// into b, which must not be modified afterwards. The whole of b is checked when it is
+// opened, so that later lookups can't fail. Data written by WriteTo, which has no lookup
+// table, can be opened too: a table is then built, which is the only allocation apart
+// from the Frozen itself. Compressed data can't be used in place.
+func FromBytes(b []byte) (*Frozen, error) {
+	if len(b) < formatHeaderSize {
+		return nil, fmt.Errorf("%w: data is truncated", ErrFormat)
+	}
+	if string(b[:4]) != formatMagic {
+		return nil, fmt.Errorf("%w: bad magic number", ErrFormat)
+	}
+	if v := binary.LittleEndian.Uint32(b[4:]); v != formatVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrFormat, v)
+	}
+	flags := binary.LittleEndian.Uint32(b[8:])
+	if flags&formatFlagGzip != 0 {
+		return nil, fmt.Errorf("%w: compressed data can't be used in place", ErrFormat)
+	}
+	if flags&^formatFlagTable != 0 {
+		return nil, fmt.Errorf("%w: unsupported flags %#x", ErrFormat, flags)
+	}
+	slots := uint64(binary.LittleEndian.Uint32(b[12:]))
+	count := binary.LittleEndian.Uint64(b[16:])
+	total := binary.LittleEndian.Uint64(b[24:])
+	if flags&formatFlagTable == 0 {
+		slots = 0
+	}
+
+	// Work out where each section starts, taking care that corrupt sizes can't overflow
+	rest := uint64(len(b) - formatHeaderSize)
+	if slots*formatSlotSize > rest {
+		return nil, fmt.Errorf("%w: sizes in the header don't match the data", ErrFormat)
+	}
+	rest -= slots * formatSlotSize
+	if count >= rest/8 || total != rest-8*(count+1) {
+		return nil, fmt.Errorf("%w: sizes in the header don't match the data", ErrFormat)
+	}
+	f := &Frozen{count: int(count)}
+	b = b[formatHeaderSize:]
+	f.table, b = b[:slots*formatSlotSize], b[slots*formatSlotSize:]
+	f.offsets, f.strings = b[:8*(count+1)], b[8*(count+1):]
+
+	var prev uint64
+	for k := range f.count + 1 {
+		offset := binary.LittleEndian.Uint64(f.offsets[8*k:])
+		if (k == 0 && offset != 0) || offset < prev {
+			return nil, fmt.Errorf("%w: bad offset %d for string %d", ErrFormat, offset, k)
+		}
+		prev = offset
+	}
+	if prev != total {
+		return nil, fmt.Errorf("%w: strings end at %d, not %d", ErrFormat, prev, total)
+	}
+
+	if slots == 0 {
+		f.table = buildFormatTable(f.count, f.Get)
+		slots = uint64(len(f.table) / formatSlotSize)
+	}
+	// Every lookup stops at an empty slot, so there must be at least one
+	if bits.OnesCount64(slots) != 1 || count >= slots {
+		return nil, fmt.Errorf("%w: bad lookup table size %d", ErrFormat, slots)
+	}
+	f.mask = slots - 1
+	var empty uint64
+	for pos := range slots {
+		k := binary.LittleEndian.Uint32(f.table[pos*formatSlotSize+4:])
+		if uint64(k) > count {
+			return nil, fmt.Errorf("%w: lookup table refers to string %d", ErrFormat, k-1)
+		}
+		if k == 0 {
+			empty++
+		}
+	}
+	// Corrupt entries could fill the table however large it is
+	if empty == 0 {
+		return nil, fmt.Errorf("%w: lookup table has no empty slots", ErrFormat)
+	}
+	return f, nil
+}
+
+// Len returns the number of strings in the dictionary
+func (f *Frozen) Len() int {
+	return f.count
+}
+
+// Get returns string k. It panics if k is out of range
+func (f *Frozen) Get(k int) string {
+	if k < 0 || k >= f.count {
+		panic(fmt.Sprintf("intern: string %d out of range [0, %d)", k, f.count))
+	}
+	start := binary.LittleEndian.Uint64(f.offsets[8*k:])
+	end := binary.LittleEndian.Uint64(f.offsets[8*k+8:])
+	if start == end {
+		return ""
+	}
+	return unsafe.String(&f.strings[start], int(end-start))
+}
+
+// Lookup returns the position of val in the dictionary and true if it is present, or
+// InvalidOffset and false if not
+func (f *Frozen) Lookup(val string) (k int, ok bool) {
+	h := XXHash64(val, 0)
+	// FromBytes makes sure there is an empty slot, but we never probe more than the
+	// whole table regardless
+	pos := h & f.mask
+	for range f.mask + 1 {
+		slot := f.table[pos*formatSlotSize:]
+		k := int(binary.LittleEndian.Uint32(slot[4:]))
+		if k == 0 {
+			return InvalidOffset, false
+		}
+		if binary.LittleEndian.Uint32(slot) == uint32(h>>32) && f.Get(k-1) == val {
+			return k - 1, true
+		}
+		pos = (pos + 1) & f.mask
+	}
+	return InvalidOffset, false
+}
+
+// Deduplicate returns the copy of val held in the dictionary if there is one, or val
+// itself if not
+func (f *Frozen) Deduplicate(val string) string {
+	if k, ok := f.Lookup(val); ok {
+		return f.Get(k)
+	}
+	return val
+}
+
+// All returns an iterator over every string and its position, in order
+func (f *Frozen) All() iter.Seq2[int, string] {
+	return func(yield func(int, string) bool) {
+		for k := range f.count {
+			if !yield(k, f.Get(k)) {
+				return
+			}
+		}
+	}
+}
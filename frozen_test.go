@@ -0,0 +1,154 @@
+package intern_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func frozenData(t *testing.T, snapshot bool, vals ...string) []byte {
+	var in intern.Intern
+	for _, val := range vals {
+		in.Save(val)
+	}
+	var b bytes.Buffer
+	var err error
+	if snapshot {
+		_, err = in.WriteSnapshot(&b)
+	} else {
+		_, err = in.WriteTo(&b)
+	}
+	assert.NoError(t, err)
+	return b.Bytes()
+}
+
+func TestFromBytes(t *testing.T) {
+	vals := []string{"hat", "", "scarf", "gloves"}
+	for j := range 100 {
+		vals = append(vals, "value-"+strconv.Itoa(j))
+	}
+
+	for _, snapshot := range []bool{true, false} {
+		f, err := intern.FromBytes(frozenData(t, snapshot, vals...))
+		assert.NoError(t, err)
+		assert.Equal(t, len(vals), f.Len())
+		for j, val := range vals {
+			assert.Equal(t, val, f.Get(j))
+			k, ok := f.Lookup(val)
+			assert.True(t, ok)
+			assert.Equal(t, j, k)
+		}
+		k, ok := f.Lookup("missing")
+		assert.False(t, ok)
+		assert.Equal(t, intern.InvalidOffset, k)
+
+		var got []string
+		for k, val := range f.All() {
+			assert.Equal(t, len(got), k)
+			got = append(got, val)
+		}
+		assert.Equal(t, vals, got)
+	}
+}
+
+func TestFromBytesZeroCopy(t *testing.T) {
+	data := frozenData(t, true, "hat", "scarf")
+	f, err := intern.FromBytes(data)
+	assert.NoError(t, err)
+
+	// The strings point into data
+	data[len(data)-1] = 'X'
+	assert.Equal(t, "scarX", f.Get(1))
+
+	val := f.Deduplicate(string([]byte("hat")))
+	assert.Equal(t, "hat", val)
+	assert.Equal(t, "missing", f.Deduplicate("missing"))
+}
+
+func TestFromBytesEmpty(t *testing.T) {
+	f, err := intern.FromBytes(frozenData(t, true))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, f.Len())
+	k, ok := f.Lookup("")
+	assert.False(t, ok)
+	assert.Equal(t, intern.InvalidOffset, k)
+	assert.Panics(t, func() { f.Get(0) })
+}
+
+func TestFromBytesInvalid(t *testing.T) {
+	good := frozenData(t, true, "hat", "scarf")
+	corrupt := func(f func(data []byte)) []byte {
+		data := bytes.Clone(good)
+		f(data)
+		return data
+	}
+	slot := func(d []byte, pos, k uint32) {
+		binary.LittleEndian.PutUint32(d[32+8*pos+4:], k)
+	}
+
+	// withTable adds an empty lookup table of the given size to data without one
+	withTable := func(slots uint32) []byte {
+		plain := frozenData(t, false, "hat", "scarf")
+		data := append(bytes.Clone(plain[:32]), make([]byte, 8*slots)...)
+		data = append(data, plain[32:]...)
+		binary.LittleEndian.PutUint32(data[8:], 1)
+		binary.LittleEndian.PutUint32(data[12:], slots)
+		return data
+	}
+
+	var gz bytes.Buffer
+	var in intern.Intern
+	in.Save("hat")
+	_, err := in.WriteCompressed(&gz, gzip.DefaultCompression)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"short", good[:10], "intern: invalid dictionary format: data is truncated"},
+		{"magic", corrupt(func(d []byte) { d[0] = 'X' }), "intern: invalid dictionary format: bad magic number"},
+		{"version", corrupt(func(d []byte) { d[4] = 2 }), "intern: invalid dictionary format: unsupported version 2"},
+		{"flags", corrupt(func(d []byte) { d[8] = 5 }), "intern: invalid dictionary format: unsupported flags 0x5"},
+		{"compressed", gz.Bytes(), "intern: invalid dictionary format: compressed data can't be used in place"},
+		{"truncated", good[:len(good)-1], "intern: invalid dictionary format: sizes in the header don't match the data"},
+		{"huge table", corrupt(func(d []byte) { d[15] = 0xff }), "intern: invalid dictionary format: sizes in the header don't match the data"},
+		{"huge count", corrupt(func(d []byte) { d[23] = 0xff }), "intern: invalid dictionary format: sizes in the header don't match the data"},
+		{"offset", corrupt(func(d []byte) { d[32+64+8] = 9 }), "intern: invalid dictionary format: bad offset 8 for string 2"},
+		{"table size", withTable(6), "intern: invalid dictionary format: bad lookup table size 6"},
+		{"table full", withTable(2), "intern: invalid dictionary format: bad lookup table size 2"},
+		{"table entry", corrupt(func(d []byte) { slot(d, 3, 7) }), "intern: invalid dictionary format: lookup table refers to string 6"},
+		{"table filled", corrupt(func(d []byte) {
+			for pos := range uint32(8) {
+				slot(d, pos, 1)
+			}
+		}), "intern: invalid dictionary format: lookup table has no empty slots"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := intern.FromBytes(test.data)
+			assert.EqualError(t, err, test.err)
+			assert.True(t, errors.Is(err, intern.ErrFormat))
+		})
+	}
+}
+
+func TestFromBytesFilledTable(t *testing.T) {
+	// One string, with both slots of a two-slot table pointing at it
+	data := frozenData(t, false, "hat")
+	data = append(bytes.Clone(data[:32]), append(make([]byte, 16), data[32:]...)...)
+	binary.LittleEndian.PutUint32(data[8:], 1)
+	binary.LittleEndian.PutUint32(data[12:], 2)
+	binary.LittleEndian.PutUint32(data[32+4:], 1)
+	binary.LittleEndian.PutUint32(data[32+12:], 1)
+
+	_, err := intern.FromBytes(data)
+	assert.EqualError(t, err, "intern: invalid dictionary format: lookup table has no empty slots")
+}
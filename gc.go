@@ -0,0 +1,125 @@
+package intern
+
+import (
+	"cmp"
+	"iter"
+	"slices"
+)
+
+// Collect drops every stored string apart from those at the offsets yielded by live,
+// and copies the survivors into new storage so that the space the others took is
+// reclaimed. Strings are never removed otherwise, so this is how a long-running process
+// that keeps meeting new strings can keep its memory use in check: periodically gather
+// the offsets it still holds, for instance in a Set, and pass them in. Offsets in live
+// that aren't stored are ignored. Strings pinned with Pin are always kept.
+//
+// Every string moves, keeping the order in which they were saved. Collect returns a map
+// from each surviving string's old offset to its new one, and callers must translate
+// any offsets they hold. Strings already returned by Get or Deduplicate stay valid, as the
+// garbage collector only frees the old storage once nothing refers to it. Tuples saved
+// with SaveTuple refer to offsets, so they are not carried over, while Dict and Codes
+// number the strings that are left afresh. Any compaction in progress is abandoned, as
+// Collect does the whole job.
+func (i *Intern) Collect(live iter.Seq[int]) map[int]int {
+	var keep Set
+	for offset := range live {
+		keep.Add(offset)
+	}
+	keep.Union(&i.pinned)
+	return i.rebuild(keep.Has)
+}
+
+// Pin marks the string at offset as one that must never be dropped, however the
+// strings to keep are chosen. It suits strings such as schema field names and enum
+// values that must always be present. Pinning a string that is already pinned has no
+// effect, and the pin moves with the string when its offset changes.
+func (i *Intern) Pin(offset int) {
+	i.pinned.Add(offset)
+}
+
+// Unpin undoes Pin, so the string at offset may be dropped again
+func (i *Intern) Unpin(offset int) {
+	i.pinned.Remove(offset)
+}
+
+// Pinned returns true if the string at offset is pinned
+func (i *Intern) Pinned(offset int) bool {
+	return i.pinned.Has(offset)
+}
+
+// rebuild keeps only the strings whose offsets satisfy keep, copying them into a new
+// arena and a table sized to hold them. It returns a map from the old offset of each
+// string kept to its new one.
+func (i *Intern) rebuild(keep func(offset int) bool) map[int]int {
+	defer traceRegion("intern.rebuild").End()
+	if i.debug != nil {
+		defer i.debugCheck("rebuild")
+	}
+	var kept []slot
+	i.eachSlot(func(s slot) {
+		if keep(s.index - 1) {
+			kept = append(kept, s)
+		} else {
+			i.evicted(s.index - 1)
+		}
+	})
+	slices.SortFunc(kept, func(a, b slot) int {
+		return cmp.Compare(a.index, b.index)
+	})
+
+	a := arena{shift: i.arena.shift, huge: i.arena.huge, meta: i.arena.meta}
+	remap := make(map[int]int, len(kept))
+	for k, s := range kept {
+		offset := a.save(i.Get(s.index - 1))
+		if a.meta {
+			// Carry over the record of how the string is used
+			*a.metaByte(offset) = *i.arena.metaByte(s.index - 1)
+		}
+		remap[s.index-1] = offset
+		kept[k].index = offset + 1
+	}
+	i.arena = a
+	i.count = len(kept)
+
+	var pinned Set
+	for offset := range i.pinned.All() {
+		if moved, ok := remap[offset]; ok {
+			pinned.Add(moved)
+		}
+	}
+	i.pinned = pinned
+	if i.evict.policy == EvictClock {
+		i.moveHand(remap)
+	}
+
+	l := 16
+	for l*i.loadLimit()/16 <= len(kept) {
+		l *= 2
+	}
+	i.oldTable, i.oldTableCursor = table{}, 0
+	// Any compaction in progress has nothing left to do
+	i.compact = nil
+	if i.cuckoo != nil {
+		i.cuckooRebuild(l, kept)
+	} else {
+		i.table = newTable(l, i.hugePages)
+		for _, s := range kept {
+			i.copyEntryToTable(i.table, s.index, s.hash)
+		}
+		if i.filter != nil {
+			i.rebuildFilter()
+		}
+	}
+	i.shared = false
+	i.tuples, i.tupleSlices = nil, nil
+	if i.fold != nil {
+		i.rebuildFold()
+	}
+	if i.dict != nil {
+		i.dict.remap(remap)
+	}
+	if i.codes != nil {
+		i.codes.remap(remap)
+	}
+	return remap
+}
@@ -0,0 +1,122 @@
+package intern_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollect(t *testing.T) {
+	for _, opts := range [][]intern.Option{nil, {intern.WithCuckoo()}, {intern.WithBloomFilter()}} {
+		in := intern.New(16, opts...)
+		var live intern.Set
+		offsets := make(map[string]int)
+		for j := range 10000 {
+			// Long enough that the strings fill several chunks
+			val := "value-" + strconv.Itoa(j) + strings.Repeat(".", 50)
+			offset := in.Save(val)
+			if j%10 == 0 {
+				live.Add(offset)
+				offsets[val] = offset
+			}
+		}
+		pad := strings.Repeat(".", 50)
+		kept := in.Get(offsets["value-0"+pad])
+		before := in.MemoryUsage()
+
+		remap := in.Collect(live.All())
+		assert.Len(t, remap, 1000)
+		assert.Equal(t, 1000, in.Len())
+		after := in.MemoryUsage()
+		assert.True(t, after.Strings < before.Strings)
+		assert.True(t, after.Table < before.Table)
+
+		for val, old := range offsets {
+			offset, ok := in.Lookup(val)
+			assert.True(t, ok)
+			assert.Equal(t, remap[old], offset)
+			assert.Equal(t, val, in.Get(offset))
+		}
+		_, ok := in.Lookup("value-1" + pad)
+		assert.False(t, ok)
+		assert.Equal(t, "value-0"+pad, kept)
+
+		// The order the strings were saved in is kept
+		var order []string
+		for _, val := range in.AllInOrder() {
+			order = append(order, val)
+		}
+		assert.Equal(t, "value-0"+pad, order[0])
+		assert.Equal(t, "value-9990"+pad, order[len(order)-1])
+
+		// New strings can still be stored
+		offset := in.Save("value-1")
+		assert.Equal(t, "value-1", in.Get(offset))
+		assert.Equal(t, 1001, in.Len())
+	}
+}
+
+func TestCollectNothing(t *testing.T) {
+	var in intern.Intern
+	in.Save("hat")
+	in.Save("scarf")
+
+	remap := in.Collect(func(yield func(int) bool) {
+		yield(12345)
+	})
+	assert.Empty(t, remap)
+	assert.Equal(t, 0, in.Len())
+	_, ok := in.Lookup("hat")
+	assert.False(t, ok)
+	assert.Equal(t, 0, in.Save("scarf"))
+}
+
+func TestCollectSnapshot(t *testing.T) {
+	var in intern.Intern
+	hat := in.Save("hat")
+	in.Save("scarf")
+	snap := in.Snapshot()
+
+	in.Collect(func(yield func(int) bool) {
+		yield(hat)
+	})
+	assert.Equal(t, 1, in.Len())
+	assert.Equal(t, 2, snap.Len())
+	offset, ok := snap.Lookup("scarf")
+	assert.True(t, ok)
+	assert.Equal(t, "scarf", snap.Get(offset))
+}
+
+func TestPin(t *testing.T) {
+	var in intern.Intern
+	hat := in.Save("hat")
+	scarf := in.Save("scarf")
+	gloves := in.Save("gloves")
+
+	in.Pin(scarf)
+	in.Pin(gloves)
+	in.Pin(gloves)
+	assert.True(t, in.Pinned(scarf))
+	assert.False(t, in.Pinned(hat))
+	in.Unpin(gloves)
+	assert.False(t, in.Pinned(gloves))
+
+	// Nothing is live, but the pinned string survives
+	remap := in.Collect(func(yield func(int) bool) {})
+	assert.Equal(t, 1, in.Len())
+	offset, ok := in.Lookup("scarf")
+	assert.True(t, ok)
+	assert.Equal(t, remap[scarf], offset)
+	assert.True(t, in.Pinned(offset))
+
+	// The pin follows the string to its new offset
+	in.Save("hat")
+	remap = in.Collect(func(yield func(int) bool) {})
+	assert.Equal(t, 1, in.Len())
+	_, ok = in.Lookup("hat")
+	assert.False(t, ok)
+	assert.True(t, in.Pinned(remap[offset]))
+}
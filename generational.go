@@ -0,0 +1,70 @@
+package intern
+
+// Generational is an interner that forgets strings that have not been seen recently. It
+// holds an active and a previous generation. Strings are saved in the active generation,
+// and Rotate retires the previous generation and starts a new active one. A string
+// survives a rotation only if it is seen again before the next one, so the age of
+// anything held is bounded by two rotation periods without tracking per-string times.
+//
+// Because the generations have separate storage there are no stable offsets. Strings
+// returned before a rotation are unaffected by it; the garbage collector reclaims a
+// retired generation once nothing refers to its strings.
+type Generational struct {
+	cap      int
+	active   *Intern
+	previous *Intern
+	// onEvict holds the callbacks registered with OnEvict, which every generation shares
+	onEvict []func(offset int, s string)
+}
+
+// NewGenerational creates a Generational interner. Each generation starts with a table
+// of size cap.
+func NewGenerational(cap int) *Generational {
+	return &Generational{
+		cap:      cap,
+		active:   New(cap),
+		previous: New(cap),
+	}
+}
+
+// Deduplicate returns a stored version of val. Within a generation this is always backed
+// by the same memory for the same string. A string carried over from the previous
+// generation is copied into the active one, so the copy returned may change when the
+// generation does.
+func (g *Generational) Deduplicate(val string) string {
+	if offset, ok := g.active.find(val); ok {
+		return g.active.Get(offset)
+	}
+	if offset, ok := g.previous.find(val); ok {
+		val = g.previous.Get(offset)
+	}
+	return g.active.Deduplicate(val)
+}
+
+// Len returns the number of unique strings in the active generation
+func (g *Generational) Len() int {
+	return g.active.Len()
+}
+
+// OnEvict registers fn to be called for each string dropped by Rotate, as Intern.OnEvict
+// does for an Intern. The offset is the string's offset in the generation being retired.
+// Callbacks run synchronously within Rotate, in the order they were registered.
+func (g *Generational) OnEvict(fn func(offset int, s string)) {
+	g.onEvict = append(g.onEvict, fn)
+	g.active.onEvict, g.previous.onEvict = g.onEvict, g.onEvict
+}
+
+// Rotate starts a new generation. Strings only held in the previous generation are
+// dropped.
+func (g *Generational) Rotate() {
+	if len(g.onEvict) != 0 {
+		for offset, val := range g.previous.All() {
+			if _, ok := g.active.find(val); !ok {
+				g.previous.evicted(offset)
+			}
+		}
+	}
+	next := New(g.cap)
+	next.onEvict = g.onEvict
+	g.previous, g.active = g.active, next
+}
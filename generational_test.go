@@ -0,0 +1,60 @@
+package intern_test
+
+import (
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerational(t *testing.T) {
+	g := intern.NewGenerational(16)
+
+	hat := g.Deduplicate("hat")
+	assert.Equal(t, "hat", hat)
+	assert.Equal(t, datapointer(hat), datapointer(g.Deduplicate("hat")))
+	g.Deduplicate("sat")
+	assert.Equal(t, 2, g.Len())
+
+	g.Rotate()
+	assert.Equal(t, 0, g.Len())
+
+	// hat is seen again so is carried into the new generation
+	hat2 := g.Deduplicate("hat")
+	assert.Equal(t, "hat", hat2)
+	assert.Equal(t, datapointer(hat2), datapointer(g.Deduplicate("hat")))
+	assert.Equal(t, 1, g.Len())
+
+	g.Rotate()
+	assert.Equal(t, 0, g.Len())
+	g.Rotate()
+
+	// sat has now been forgotten completely, but is still stored correctly
+	assert.Equal(t, "sat", g.Deduplicate("sat"))
+	assert.Equal(t, 1, g.Len())
+
+	// Strings from old generations are still valid
+	assert.Equal(t, "hat", hat)
+}
+
+func TestGenerationalOnEvict(t *testing.T) {
+	g := intern.NewGenerational(16)
+
+	var evicted []string
+	g.OnEvict(func(offset int, s string) {
+		evicted = append(evicted, s)
+	})
+
+	g.Deduplicate("hat")
+	g.Deduplicate("sat")
+	g.Rotate()
+	assert.Empty(t, evicted)
+
+	g.Deduplicate("hat")
+	g.Deduplicate("mat")
+	g.Rotate()
+	assert.Equal(t, []string{"sat"}, evicted)
+
+	g.Rotate()
+	assert.ElementsMatch(t, []string{"sat", "hat", "mat"}, evicted)
+}
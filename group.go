@@ -0,0 +1,97 @@
+package intern
+
+import (
+	"encoding/binary"
+	"fmt"
+	"iter"
+)
+
+// Groups groups rows by key columns of string offsets, as for a GROUP BY. Equal strings
+// share one offset, so rows are grouped by comparing integers and no string is read.
+// Each distinct key is given a group number, from 0 up in the order the keys were first
+// seen, which callers can use to index slices of their own aggregates, and the rows in
+// each group are counted.
+type Groups struct {
+	width int
+	// keys holds the key of each group, width offsets at a time
+	keys   []int
+	counts []int
+	// index maps each key, encoded as varints, to its group number
+	index   map[string]int
+	scratch []byte
+}
+
+// NewGroups returns an empty Groups for keys of width offsets
+func NewGroups(width int) *Groups {
+	return &Groups{width: width, index: make(map[string]int)}
+}
+
+// GroupColumns groups the rows of columns, where columns[c][r] is the offset in column c
+// of row r. All the columns must be the same length.
+func GroupColumns(columns ...[]int) *Groups {
+	g := NewGroups(len(columns))
+	if len(columns) == 0 {
+		return g
+	}
+	key := make([]int, len(columns))
+	for r := range columns[0] {
+		for c, column := range columns {
+			key[c] = column[r]
+		}
+		g.Add(key...)
+	}
+	return g
+}
+
+// Add counts a row with the given key and returns the number of its group. It panics
+// if the key isn't the width the Groups was made for.
+func (g *Groups) Add(key ...int) int {
+	return g.AddN(1, key...)
+}
+
+// AddN is like Add, but counts n rows
+func (g *Groups) AddN(n int, key ...int) int {
+	if len(key) != g.width {
+		panic(fmt.Sprintf("intern: group key has %d offsets, expected %d", len(key), g.width))
+	}
+	b := g.scratch[:0]
+	for _, offset := range key {
+		b = binary.AppendUvarint(b, uint64(offset))
+	}
+	g.scratch = b
+	group, ok := g.index[string(b)]
+	if !ok {
+		group = len(g.counts)
+		g.index[string(b)] = group
+		g.keys = append(g.keys, key...)
+		g.counts = append(g.counts, 0)
+	}
+	g.counts[group] += n
+	return group
+}
+
+// Len returns the number of groups
+func (g *Groups) Len() int {
+	return len(g.counts)
+}
+
+// Key returns the key of a group. It is shared with g, so must not be modified.
+func (g *Groups) Key(group int) []int {
+	return g.keys[group*g.width : (group+1)*g.width : (group+1)*g.width]
+}
+
+// Count returns the number of rows in a group
+func (g *Groups) Count(group int) int {
+	return g.counts[group]
+}
+
+// All returns an iterator over the key and row count of each group, in group order
+func (g *Groups) All() iter.Seq2[[]int, int] {
+	return func(yield func([]int, int) bool) {
+		for group, count := range g.counts {
+			if !yield(g.Key(group), count) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package intern_test
+
+import (
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupColumns(t *testing.T) {
+	in := intern.New(16)
+	country := in.SaveAll([]string{"uk", "fr", "uk", "uk", "fr"}, nil)
+	device := in.SaveAll([]string{"ios", "ios", "web", "ios", "ios"}, nil)
+	spend := []int{10, 20, 30, 40, 50}
+
+	g := intern.GroupColumns(country, device)
+	assert.Equal(t, 3, g.Len())
+
+	type row struct {
+		country, device string
+		count           int
+	}
+	var rows []row
+	for key, count := range g.All() {
+		rows = append(rows, row{in.Get(key[0]), in.Get(key[1]), count})
+	}
+	assert.Equal(t, []row{{"uk", "ios", 2}, {"fr", "ios", 2}, {"uk", "web", 1}}, rows)
+
+	// Group numbers index aggregates kept by the caller
+	g = intern.NewGroups(2)
+	totals := make([]int, 0)
+	for r := range spend {
+		group := g.Add(country[r], device[r])
+		if group == len(totals) {
+			totals = append(totals, 0)
+		}
+		totals[group] += spend[r]
+	}
+	assert.Equal(t, []int{50, 70, 30}, totals)
+	assert.Equal(t, []int{country[1], device[1]}, g.Key(1))
+
+	assert.Equal(t, 1, g.AddN(3, country[1], device[1]))
+	assert.Equal(t, 5, g.Count(1))
+	assert.Panics(t, func() { g.Add(country[0]) })
+	assert.Equal(t, 0, intern.GroupColumns().Len())
+}
@@ -0,0 +1,136 @@
+package intern
+
+import (
+	"hash/maphash"
+	"math/rand/v2"
+)
+
+// Hasher is a string hash function. The seed selects one of a family of hash functions:
+// the same string and seed must always give the same hash, and a different seed should
+// change which strings collide. Rehash picks a new seed.
+type Hasher func(val string, seed uint64) uint64
+
+// Maphash returns a Hasher based on hash/maphash. Each Hasher returned has its own random
+// maphash seed.
+func Maphash() Hasher {
+	s := maphash.MakeSeed()
+	return func(val string, seed uint64) uint64 {
+		return maphashString(s, val, seed)
+	}
+}
+
+// maphashString hashes val with hash/maphash. maphash seeds are opaque, so we mix our
+// seed into the result instead
+func maphashString(s maphash.Seed, val string, seed uint64) uint64 {
+	return mix(maphash.String(s, val) ^ seed)
+}
+
+// inlineLen is the length below which strings are kept in the table in place of a hash
+const inlineLen = 8
+
+// hash returns the hash of val used to place it in the table. We replace the top byte of
+// the hash with the length of the string (up to 255), so when probing the table an entry
+// whose length differs is rejected without reading the string from the arena.
+//
+// Strings shorter than inlineLen fit in the remaining 7 bytes, so for those we use the
+// string itself instead of a hash. Such strings are common, we save hashing them, and
+// if the value in the table matches they are equal without reading the arena. The
+// strings are still saved in the arena too, so offsets work as normal.
+func (i *Intern) hash(val string) uint64 {
+	return hashString(val, i.hasher, i.seed)
+}
+
+// hashString is the implementation of Intern.hash. If hasher is nil the default hash is
+// used.
+func hashString(val string, hasher Hasher, seed uint64) uint64 {
+	if len(val) < inlineLen {
+		var hash uint64
+		for j := 0; j < len(val); j++ {
+			hash |= uint64(val[j]) << (8 * j)
+		}
+		return hash | uint64(len(val))<<56
+	}
+
+	var hash uint64
+	if hasher != nil {
+		hash = hasher(val, seed)
+	} else {
+		hash = defaultHash(val, seed)
+	}
+	return hash&(1<<56-1) | uint64(min(len(val), 255))<<56
+}
+
+// isInline returns true if hash holds a whole string rather than a hash of it
+func isInline(hash uint64) bool {
+	return hash>>56 < inlineLen
+}
+
+// spread turns the value from hash into the pseudo-random number we use to choose where
+// in the table to start looking. Inline strings need mixing to spread them out, and we
+// mix in the seed so that Rehash changes where they go too.
+func (i *Intern) spread(hash uint64) uint64 {
+	return mix(hash ^ i.seed)
+}
+
+// Rehash rebuilds the hash table using a new random hash seed. Stored strings and their
+// offsets are unchanged. This is a mitigation if probe lengths show that the strings
+// being saved collide unusually often, whether by bad luck or by design. Any resize in
+// progress is completed as part of the rebuild, as is any compaction.
+func (i *Intern) Rehash() {
+	defer traceRegion("intern.rehash").End()
+	if i.debug != nil {
+		defer i.debugCheck("rehash")
+	}
+	if i.compact != nil {
+		i.finishMigration()
+	}
+	i.seed = rand.Uint64()
+
+	if i.cuckoo != nil {
+		entries := make([]slot, 0, i.count)
+		i.each(func(offset int) {
+			entries = append(entries, slot{hash: i.hash(i.Get(offset)), index: offset + 1})
+		})
+		i.cuckooRebuild(i.cuckoo.len(), entries)
+		return
+	}
+
+	l := max(i.table.len(), 16)
+	t := newTable(l, i.hugePages)
+	i.each(func(offset int) {
+		i.copyEntryToTable(t, offset+1, i.hash(i.Get(offset)))
+	})
+	i.table = t
+	i.oldTable = table{}
+	i.oldTableCursor = 0
+	if i.filter != nil {
+		i.rebuildFilter()
+	}
+}
+
+// fnvHash is a seeded FNV-1a hash with a final mixing step so the low bits we use to
+// pick a slot depend on every byte. Unlike the runtime's hash it gives the same result in
+// every process.
+func fnvHash(val string, seed uint64) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+	h := uint64(offset64) ^ seed
+	for j := 0; j < len(val); j++ {
+		h ^= uint64(val[j])
+		h *= prime64
+	}
+	return mix(h)
+}
+
+// mix is the murmur3 finalizer. It spreads the effect of every input bit across the
+// output
+func mix(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h
+}
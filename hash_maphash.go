@@ -0,0 +1,15 @@
+//go:build !intern_memhash
+
+package intern
+
+import "hash/maphash"
+
+// processSeed seeds the default hash
+var processSeed = maphash.MakeSeed()
+
+// defaultHash is used unless a Hasher is configured. By default this is hash/maphash,
+// so the package only depends on the standard library's supported APIs. Build with the
+// intern_memhash tag to call the runtime's hash function directly instead.
+func defaultHash(val string, seed uint64) uint64 {
+	return maphashString(processSeed, val, seed)
+}
@@ -0,0 +1,18 @@
+//go:build intern_memhash
+
+package intern
+
+import "unsafe"
+
+// We use the runtime's map hash function without the overhead of using
+// hash/maphash. This is the AES-based hash on platforms that support it.
+//
+//go:linkname runtime_memhash runtime.memhash
+//go:noescape
+func runtime_memhash(p unsafe.Pointer, seed, s uintptr) uintptr
+
+// defaultHash is used unless a Hasher is configured. Note the runtime's hash is only 32
+// bits on 32-bit platforms
+func defaultHash(val string, seed uint64) uint64 {
+	return uint64(runtime_memhash(unsafe.Pointer(unsafe.StringData(val)), uintptr(seed), uintptr(len(val))))
+}
@@ -0,0 +1,204 @@
+package intern_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRehash(t *testing.T) {
+	for _, opts := range [][]intern.Option{
+		nil,
+		{intern.WithDeterministic(1)},
+		{intern.WithHasher(intern.Maphash())},
+	} {
+		in := intern.New(16, opts...)
+
+		offsets := make(map[string]int)
+		// Stop part way through a resize so that the rebuild has to complete it
+		for j := 0; j < 1546; j++ {
+			val := strconv.Itoa(j)
+			offsets[val] = in.Save(val)
+		}
+
+		in.Rehash()
+		assert.Equal(t, 1546, in.Len())
+		for val, offset := range offsets {
+			assert.Equal(t, offset, in.Save(val))
+		}
+		assert.Equal(t, 1546, in.Len())
+
+		for j := 1546; j < 3000; j++ {
+			val := strconv.Itoa(j)
+			assert.Equal(t, val, in.Get(in.Save(val)))
+		}
+		assert.Equal(t, 3000, in.Len())
+	}
+}
+
+func TestRehashEmpty(t *testing.T) {
+	var in intern.Intern
+	in.Rehash()
+	assert.Equal(t, "hat", in.Deduplicate("hat"))
+}
+
+func TestHashers(t *testing.T) {
+	tests := []struct {
+		name   string
+		hasher intern.Hasher
+	}{
+		{"maphash", intern.Maphash()},
+		{"xxhash64", intern.XXHash64},
+		{"wyhash", intern.Wyhash},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.hasher("hat", 1), test.hasher("hat", 1))
+			assert.NotEqual(t, test.hasher("hat", 1), test.hasher("hat", 2))
+			assert.NotEqual(t, test.hasher("hat", 1), test.hasher("sat", 1))
+
+			in := intern.New(16, intern.WithHasher(test.hasher))
+			for j := 0; j < 2; j++ {
+				for k := 0; k < 1000; k++ {
+					val := strconv.Itoa(k)
+					assert.Equal(t, val, in.Deduplicate(val))
+				}
+			}
+			assert.Equal(t, 1000, in.Len())
+		})
+	}
+}
+
+func TestXXHash64(t *testing.T) {
+	tests := []struct {
+		val      string
+		hash     uint64
+		seedHash uint64
+	}{
+		{"", 0xef46db3751d8e999, 0x98b1582b0977e704},
+		{"a", 0xd24ec4f1a98c6e5b, 0x88e4fe59adf7b0cc},
+		{"abc", 0x44bc2cf5ad770999, 0x13c1d910702770e6},
+		{"hello world!", 0x9bb9a01dc10f4709, 0x83538bcf49fbf962},
+		{"0123456789abcdef0123456789abcdef", 0x642a94958e71e6c5, 0x666b1f69d2455da5},
+		{strings.Repeat("xyz", 37), 0x2b6dbf91baeb6094, 0x308dbf432123f28},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.hash, intern.XXHash64(test.val, 0), test.val)
+		assert.Equal(t, test.seedHash, intern.XXHash64(test.val, 42), test.val)
+	}
+}
+
+func TestWyhashLengths(t *testing.T) {
+	// Each length takes a slightly different path. Check every byte affects the result
+	data := []byte(strings.Repeat("abcdefghijklmnopqrstuvwxyz", 5))
+	for n := 1; n <= len(data); n++ {
+		val := string(data[:n])
+		h := intern.Wyhash(val, 0)
+		for j := 0; j < n; j++ {
+			changed := []byte(val)
+			changed[j] ^= 1
+			assert.NotEqual(t, h, intern.Wyhash(string(changed), 0), "length %d byte %d", n, j)
+		}
+	}
+}
+
+func benchmarkHasher(b *testing.B, opts ...intern.Option) {
+	s := make([]string, b.N)
+	for i := range s {
+		s[i] = strconv.Itoa(i)
+	}
+
+	in := intern.New(16, opts...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for _, v := range s {
+		in.Save(v)
+	}
+}
+
+func BenchmarkHasherDefault(b *testing.B) {
+	benchmarkHasher(b)
+}
+
+func BenchmarkHasherMaphash(b *testing.B) {
+	benchmarkHasher(b, intern.WithHasher(intern.Maphash()))
+}
+
+func BenchmarkHasherXXHash64(b *testing.B) {
+	benchmarkHasher(b, intern.WithHasher(intern.XXHash64))
+}
+
+func BenchmarkHasherWyhash(b *testing.B) {
+	benchmarkHasher(b, intern.WithHasher(intern.Wyhash))
+}
+
+func BenchmarkHasherDeterministic(b *testing.B) {
+	benchmarkHasher(b, intern.WithDeterministic(0))
+}
+
+func BenchmarkHash(b *testing.B) {
+	hashers := []struct {
+		name   string
+		hasher intern.Hasher
+	}{
+		{"maphash", intern.Maphash()},
+		{"xxhash64", intern.XXHash64},
+		{"wyhash", intern.Wyhash},
+	}
+
+	for _, size := range []int{2, 8, 16, 64, 1024} {
+		val := strings.Repeat("x", size)
+		for _, h := range hashers {
+			b.Run(h.name+"/"+strconv.Itoa(size), func(b *testing.B) {
+				b.SetBytes(int64(size))
+				var total uint64
+				for range b.N {
+					total += h.hasher(val, 0)
+				}
+				_ = total
+			})
+		}
+	}
+}
+
+func TestCollidingHashes(t *testing.T) {
+	// Every string has the same hash, so we have to rely on lengths and then comparing
+	// the strings themselves
+	in := intern.New(16, intern.WithHasher(func(val string, seed uint64) uint64 {
+		return 42
+	}))
+
+	for j := 0; j < 2; j++ {
+		for k := 0; k < 200; k++ {
+			// Short strings aren't hashed, so make sure these are long enough to be
+			val := "collision" + strconv.Itoa(k)
+			assert.Equal(t, val, in.Deduplicate(val))
+		}
+	}
+	assert.Equal(t, 200, in.Len())
+}
+
+func TestShortStrings(t *testing.T) {
+	in := intern.New(16)
+
+	// Strings under 8 bytes are held in the table in full. Make sure ones that differ only
+	// in length or a single byte are kept apart
+	vals := []string{"", "\x00", "\x00\x00", "a", "a\x00", "ab", "abcdefg", "abcdefh", "abcdefgh", "bbcdefg"}
+	offsets := make([]int, len(vals))
+	for j, val := range vals {
+		offsets[j] = in.Save(val)
+	}
+	assert.Equal(t, len(vals), in.Len())
+	for j, val := range vals {
+		assert.Equal(t, offsets[j], in.Save(val))
+		assert.Equal(t, val, in.Get(offsets[j]))
+		assert.Equal(t, datapointer(in.Get(offsets[j])), datapointer(in.Deduplicate(val)))
+	}
+}
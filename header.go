@@ -0,0 +1,21 @@
+package intern
+
+import "net/textproto"
+
+// DeduplicateMIMEHeader interns the keys of h in place, along with the values of the
+// headers named in values, which are canonicalised as textproto does. Keys repeat
+// across every message, but most values, such as message IDs and dates, are unique, so
+// only name the headers whose values repeat, such as Content-Type or User-Agent. An
+// http.Header can be converted to a textproto.MIMEHeader to pass it here.
+func (i *Intern) DeduplicateMIMEHeader(h textproto.MIMEHeader, values ...string) {
+	for k, v := range h {
+		k = i.Deduplicate(k)
+		// Assigning with an equal key replaces the key stored in the map
+		h[k] = v
+	}
+	for _, name := range values {
+		if v, ok := h[textproto.CanonicalMIMEHeaderKey(name)]; ok {
+			i.DeduplicateInPlace(v)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package intern_test
+
+import (
+	"bufio"
+	"net/textproto"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func readHeader(t *testing.T, msg string) textproto.MIMEHeader {
+	h, err := textproto.NewReader(bufio.NewReader(strings.NewReader(msg))).ReadMIMEHeader()
+	assert.NoError(t, err)
+	return h
+}
+
+func TestDeduplicateMIMEHeader(t *testing.T) {
+	in := intern.New(16)
+	const msg = "Content-Type: text/plain\r\nMessage-Id: <1@example.com>\r\n\r\n"
+	h1, h2 := readHeader(t, msg), readHeader(t, msg)
+	in.DeduplicateMIMEHeader(h1, "content-type")
+	in.DeduplicateMIMEHeader(h2, "content-type")
+	assert.Equal(t, readHeader(t, msg), h1)
+
+	for k := range h2 {
+		assert.Equal(t, unsafe.StringData(in.Deduplicate(k)), unsafe.StringData(k), k)
+	}
+	assert.Equal(t, unsafe.StringData(h1["Content-Type"][0]), unsafe.StringData(h2["Content-Type"][0]))
+	_, ok := in.Lookup("<1@example.com>")
+	assert.False(t, ok)
+	assert.Equal(t, 3, in.Len())
+}
@@ -0,0 +1,164 @@
+package intern
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrHostname is returned when a hostname can't be converted to ASCII
+var ErrHostname = errors.New("intern: invalid hostname")
+
+// DeduplicateHostname interns host in a canonical form, lower case and without the
+// trailing dot of a fully qualified name, so that "Example.COM." and "example.com" share
+// one string. Only ASCII letters are lowered, which is all that DNS itself compares
+// without regard to case. Use DeduplicateHostnameASCII for names that may hold other
+// characters.
+func (i *Intern) DeduplicateHostname(host string) string {
+	host = strings.TrimRight(host, ".")
+	key := i.scratch[:0]
+	for j := 0; j < len(host); j++ {
+		c := host[j]
+		if 'A' <= c && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		key = append(key, c)
+	}
+	i.scratch = key
+	return i.DeduplicateBytes(key)
+}
+
+// DeduplicateHostnameASCII is like DeduplicateHostname, but also lowers non-ASCII
+// letters and converts each label that isn't plain ASCII to punycode, so that
+// "Bücher.example" is stored as "xn--bcher-kva.example". This is the form names take
+// on the wire. It doesn't apply the rest of the IDNA mapping rules, such as Unicode
+// normalisation; pass names through golang.org/x/net/idna first if they need it.
+func (i *Intern) DeduplicateHostnameASCII(host string) (string, error) {
+	host = strings.TrimRight(host, ".")
+	if !utf8.ValidString(host) {
+		return "", fmt.Errorf("%w: %q is not valid UTF-8", ErrHostname, host)
+	}
+	key := i.scratch[:0]
+	for j, label := range strings.Split(host, ".") {
+		if j > 0 {
+			key = append(key, '.')
+		}
+		label = strings.ToLower(label)
+		if isASCII(label) {
+			key = append(key, label...)
+			continue
+		}
+		var err error
+		if key, err = appendPunycode(key, label); err != nil {
+			i.scratch = key
+			return "", fmt.Errorf("%w: label %q: %w", ErrHostname, label, err)
+		}
+	}
+	i.scratch = key
+	return i.DeduplicateBytes(key), nil
+}
+
+// isASCII returns true if val holds only ASCII characters
+func isASCII(val string) bool {
+	for j := 0; j < len(val); j++ {
+		if val[j] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Punycode parameters, from RFC 3492
+const (
+	punyBase        = 36
+	punyTMin        = 1
+	punyTMax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+)
+
+// appendPunycode appends label to dst in the "xn--" punycode form used for
+// internationalised domain names, following RFC 3492
+func appendPunycode(dst []byte, label string) ([]byte, error) {
+	runes := []rune(label)
+	dst = append(dst, "xn--"...)
+	basic := 0
+	for _, r := range runes {
+		if r < utf8.RuneSelf {
+			dst = append(dst, byte(r))
+			basic++
+		}
+	}
+	if basic > 0 {
+		dst = append(dst, '-')
+	}
+
+	n, delta, bias := rune(punyInitialN), 0, punyInitialBias
+	for h := basic; h < len(runes); {
+		// Find the smallest code point not yet handled
+		m := rune(math.MaxInt32)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+		if int(m-n) > (math.MaxInt32-delta)/(h+1) {
+			return dst, errors.New("punycode overflow")
+		}
+		delta += int(m-n) * (h + 1)
+		n = m
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+			// Write delta as a variable-length integer
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := min(max(k-bias, punyTMin), punyTMax)
+				if q < t {
+					break
+				}
+				dst = append(dst, punyDigit(t+(q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			dst = append(dst, punyDigit(q))
+			bias = punyAdapt(delta, h+1, h == basic)
+			delta = 0
+			h++
+		}
+		delta++
+		n++
+	}
+	return dst, nil
+}
+
+// punyAdapt works out the bias for the next delta
+func punyAdapt(delta, points int, first bool) int {
+	if first {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / points
+	k := 0
+	for delta > (punyBase-punyTMin)*punyTMax/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+// punyDigit returns the character for a punycode digit
+func punyDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
@@ -0,0 +1,46 @@
+package intern_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeduplicateHostname(t *testing.T) {
+	in := intern.New(16)
+	for _, host := range []string{"example.com", "Example.COM.", "EXAMPLE.com.."} {
+		assert.Equal(t, datapointer(in.Deduplicate("example.com")), datapointer(in.DeduplicateHostname(host)), host)
+	}
+	assert.Equal(t, "", in.DeduplicateHostname("."))
+	// Only ASCII letters are lowered
+	assert.Equal(t, "bÜcher.example", in.DeduplicateHostname("BÜcher.Example"))
+	assert.Equal(t, 3, in.Len())
+}
+
+func TestDeduplicateHostnameASCII(t *testing.T) {
+	in := intern.New(16)
+	tests := []struct {
+		host string
+		exp  string
+	}{
+		{host: "Example.COM.", exp: "example.com"},
+		{host: "Bücher.example", exp: "xn--bcher-kva.example"},
+		{host: "MÜNCHEN.de.", exp: "xn--mnchen-3ya.de"},
+		{host: "www.中国", exp: "www.xn--fiqs8s"},
+		{host: "テスト", exp: "xn--zckzah"},
+	}
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			host, err := in.DeduplicateHostnameASCII(test.host)
+			assert.NoError(t, err)
+			assert.Equal(t, test.exp, host)
+			assert.Equal(t, datapointer(in.Deduplicate(test.exp)), datapointer(host))
+		})
+	}
+
+	_, err := in.DeduplicateHostnameASCII("bad\xff.example")
+	assert.True(t, errors.Is(err, intern.ErrHostname))
+	assert.EqualError(t, err, `intern: invalid hostname: "bad\xff.example" is not valid UTF-8`)
+}
@@ -0,0 +1,30 @@
+package intern
+
+import "unsafe"
+
+// hugePageSize is the size of a transparent huge page on common platforms
+const hugePageSize = 2 << 20
+
+// allocBytes returns n zeroed bytes. If huge is set and n is at least a huge page, the
+// memory is aligned to a huge page boundary and the kernel is asked to back it with huge
+// pages. This over-allocates by up to a huge page, so is only worth it for big arrays, and
+// is skipped on platforms without huge pages.
+func allocBytes(n int, huge bool) []byte {
+	if !huge || !hugePagesSupported || n < hugePageSize {
+		return make([]byte, n)
+	}
+	b := make([]byte, n+hugePageSize)
+	start := -int(uintptr(unsafe.Pointer(unsafe.SliceData(b)))) & (hugePageSize - 1)
+	b = b[start : start+n : start+n]
+	adviseHugePages(b)
+	return b
+}
+
+// allocSlots returns a zeroed slice of n slots, allocated as allocBytes does
+func allocSlots(n int, huge bool) []slot {
+	if !huge {
+		return make([]slot, n)
+	}
+	b := allocBytes(n*int(unsafe.Sizeof(slot{})), huge)
+	return unsafe.Slice((*slot)(unsafe.Pointer(unsafe.SliceData(b))), n)
+}
@@ -0,0 +1,12 @@
+package intern
+
+import "syscall"
+
+// hugePagesSupported is true where adviseHugePages can ask for huge pages
+const hugePagesSupported = true
+
+// adviseHugePages asks the kernel to back b with transparent huge pages. This is only
+// advice, so errors are ignored: the memory works just the same without them.
+func adviseHugePages(b []byte) {
+	_ = syscall.Madvise(b, syscall.MADV_HUGEPAGE)
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package intern
+
+// hugePagesSupported is false where adviseHugePages does nothing, so there is no point
+// aligning memory to huge pages. That matters most on js/wasm and wasip1, where memory is
+// scarce and never handed back
+const hugePagesSupported = false
+
+// adviseHugePages does nothing on platforms where we don't know how to ask for huge pages
+func adviseHugePages(b []byte) {}
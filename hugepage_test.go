@@ -0,0 +1,29 @@
+package intern_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHugePages(t *testing.T) {
+	for _, opts := range [][]intern.Option{
+		{intern.WithHugePages()},
+		{intern.WithHugePages(), intern.WithCuckoo()},
+	} {
+		in := intern.New(1<<18, opts...)
+		assert.Equal(t, 1<<18, in.Cap())
+
+		offsets := make([]int, 300000)
+		for j := range offsets {
+			offsets[j] = in.Save(strconv.Itoa(j))
+		}
+		for j, offset := range offsets {
+			assert.Equal(t, strconv.Itoa(j), in.Get(offset))
+		}
+		// Strings are stored in chunks of one huge page
+		assert.Equal(t, 2<<20, in.MemoryUsage().Strings)
+	}
+}
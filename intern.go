@@ -5,41 +5,93 @@
 package intern
 
 import (
+	"math"
 	"math/bits"
-	"reflect"
+	"math/rand/v2"
+	"slices"
 	"unsafe"
-
-	"github.com/philpearl/stringbank"
 )
 
-// We use the runtime's map hash function without the overhead of using
-// hash/maphash
-//go:linkname runtime_memhash runtime.memhash
-//go:noescape
-func runtime_memhash(p unsafe.Pointer, seed, s uintptr) uintptr
+// InvalidOffset is never the offset of a stored string. Offset 0 is the first string
+// saved, so use InvalidOffset rather than 0 to mean "no string" in fields and variables
+// that hold offsets. Lookups return it when the string isn't found.
+const InvalidOffset = -1
 
 // Intern implements the interner. Allocate it
 type Intern struct {
-	stringbank.Stringbank
+	arena
 	table          table
 	oldTable       table
 	count          int
 	oldTableCursor int
+	maxBytes       int
+	entryLimit     int
+	onInsert       []func(offset int, s string)
+	onEvict        []func(offset int, s string)
+	probes         probeStats
+	seed           uint64
+	// hasher replaces the default hash function if set
+	hasher Hasher
+	// filter lets us skip probing the table for most strings that aren't present
+	filter *bloomFilter
+	// cuckoo replaces table if set
+	cuckoo *cuckooTable
+	// hugePages asks for large tables to be backed by huge pages
+	hugePages bool
+	growth    adaptiveGrowth
+	// shared is set when a Snapshot shares the table
+	shared bool
+	// deferMigration leaves copying entries to the new table during a resize to someone
+	// other than the writer
+	deferMigration bool
+	// tuples holds the tuples saved by SaveTuple, each encoded as a string of offsets.
+	// tupleSlices holds the slices returned by DeduplicateSlice
+	tuples      *Intern
+	tupleSlices map[int][]string
+	// scratch is reused to build tuple and composite keys
+	scratch []byte
+	// pinned holds the offsets of strings that must never be dropped
+	pinned Set
+	evict  eviction
+	// compact is set while an incremental compaction is in progress
+	compact *compaction
+	// profile records where inserts come from, if WithInsertProfile is used
+	profile *insertProfile
+	// recorder records the strings saved and looked up, if WithRecorder is used
+	recorder *Recorder
+	// debug is set by WithDebug
+	debug *debugState
+	// collator orders strings in place of strings.Compare, if WithCollator is used
+	collator Collator
+	// fold is the case-insensitive index kept by WithFoldIndex
+	fold *foldIndex
+	// dict and codes are the views made by Dict and Codes
+	dict  *Dict
+	codes *Codes
 }
 
 // New creates a new interning table
-func New(cap int) *Intern {
+func New(cap int, opts ...Option) *Intern {
 	if cap < 16 {
 		cap = 16
 	} else {
-		cap = 1 << uint(64-bits.LeadingZeros(uint(cap-1)))
+		cap = 1 << bits.Len(uint(cap-1))
+	}
+	i := &Intern{
+		seed: rand.Uint64(),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	if i.cuckoo != nil {
+		i.cuckoo = newCuckooTable(cap, i.hugePages)
+	} else {
+		i.table = newTable(cap, i.hugePages)
 	}
-	return &Intern{
-		table: table{
-			hashes:  make([]uint32, cap),
-			indices: make([]int, cap),
-		},
+	if i.filter != nil {
+		i.rebuildFilter()
 	}
+	return i
 }
 
 // Len returns the number of unique strings stored
@@ -49,60 +101,373 @@ func (i *Intern) Len() int {
 
 // Cap returns the size of the intern table
 func (i *Intern) Cap() int {
+	if i.cuckoo != nil {
+		return i.cuckoo.len()
+	}
 	return i.table.len()
 }
 
 // Deduplicate takes a string and returns a permanently stored version. This will always
-// be backed by the same memory for the same string.
+// be backed by the same memory for the same string. If the string is new and can't be
+// stored without exceeding the memory budget, it is returned as it is.
 func (i *Intern) Deduplicate(val string) string {
-	return i.Get(i.Save(val))
+	offset, err := i.TrySave(val)
+	if err != nil {
+		return val
+	}
+	return i.Get(offset)
+}
+
+// DeduplicateBytes is like Deduplicate, but takes the string as a byte slice, which
+// is only read. No string is allocated unless b is new and can't be stored, in which case
+// a copy of b is returned.
+func (i *Intern) DeduplicateBytes(b []byte) string {
+	offset, err := i.saveBytes(b)
+	if err != nil {
+		return string(b)
+	}
+	return i.Get(offset)
+}
+
+// TryDeduplicate is like Deduplicate, but reports why a new string couldn't be stored.
+// The error is ErrMaxBytes if storing it would exceed the memory budget, or ErrFull if the
+// limit on the number of strings has been reached. val is returned as it is alongside the
+// error, so the caller can carry on with an un-interned string.
+func (i *Intern) TryDeduplicate(val string) (string, error) {
+	offset, err := i.TrySave(val)
+	if err != nil {
+		return val, err
+	}
+	return i.Get(offset), nil
+}
+
+// AppendGet appends the bytes of the string stored at offset to dst and returns the
+// extended buffer.
+func (i *Intern) AppendGet(dst []byte, offset int) []byte {
+	return append(dst, i.Get(offset)...)
+}
+
+// GetBytes returns the string stored at offset as a byte slice that shares the
+// arena's memory, so no copy is made. The slice must never be modified: every string
+// returned for the same offset is backed by the same bytes.
+func (i *Intern) GetBytes(offset int) []byte {
+	val := i.Get(offset)
+	return unsafe.Slice(unsafe.StringData(val), len(val))
 }
 
 // Save stores a string in out deduplicated string store, and returns an integer offset
-// for accessing it.
+// for accessing it. Save panics if the string is new and storing it would exceed the
+// memory budget. Use TrySave to handle that case.
 func (i *Intern) Save(val string) int {
-	// we use a hashtable where the keys are stringbank offsets, but comparisons are done on
-	// strings. There is no value to store
+	offset, err := i.TrySave(val)
+	if err != nil {
+		panic(err)
+	}
+	return offset
+}
+
+// OffsetForBytes is like Save, but takes the string as a byte slice, which is only read.
+// It suits encoders that hold values as []byte, as no string is allocated to find the
+// offset.
+func (i *Intern) OffsetForBytes(b []byte) int {
+	offset, err := i.saveBytes(b)
+	if err != nil {
+		panic(err)
+	}
+	return offset
+}
+
+// TrySave is like Save, but returns InvalidOffset with ErrMaxBytes, ErrFull or ErrTooLarge
+// rather than panicking if the string can't be stored.
+func (i *Intern) TrySave(val string) (int, error) {
 	i.resize()
+	return i.save(val)
+}
 
-	hash := uint32(runtime_memhash(
-		unsafe.Pointer((*reflect.StringHeader)(unsafe.Pointer(&val)).Data),
-		0,
-		uintptr(len(val)),
-	))
+// save stores a string without first checking whether the table needs to grow. The
+// caller must make sure there is room.
+func (i *Intern) save(val string) (int, error) {
+	return i.saveHash(val, i.hash(val))
+}
 
-	if i.oldTable.len() != 0 {
-		_, index := i.findInTable(i.oldTable, val, hash)
-		if index != 0 {
-			return index - 1
-		}
+// saveHash is save for a string whose hash has already been calculated
+func (i *Intern) saveHash(val string, hash uint64) (int, error) {
+	if i.recorder != nil {
+		i.recorder.record(opSave, val)
+	}
+	// we use a hashtable where the keys are arena offsets, but comparisons are done on
+	// strings. There is no value to store
+	var cursor int
+	var index int
+	if i.cuckoo != nil {
+		index = i.cuckooFind(val, hash)
+	} else {
+		index, cursor = i.findLinear(val, hash)
 	}
-
-	cursor, index := i.findInTable(i.table, val, hash)
 	if index != 0 {
-		return index - 1
+		if i.arena.meta {
+			i.touch(index - 1)
+		}
+		if i.compact != nil {
+			i.compact.live.Add(index - 1)
+		}
+		if i.debug != nil {
+			i.debugSaved("save (found)", val, index-1)
+		}
+		return index - 1, nil
 	}
 
 	// String was not found, so we want to store it. Cursor is the index where we should
 	// store it
-	offset := i.Stringbank.Save(val)
-	i.table.hashes[cursor] = hash
-	i.table.indices[cursor] = offset + 1
+	if err := i.checkBudget(val); err != nil {
+		return InvalidOffset, err
+	}
+	offset := i.arena.save(val)
+	if i.arena.meta {
+		i.inserted(offset)
+	}
+	if i.profile != nil {
+		i.profile.record(i.arena.space(len(val)))
+	}
+	s := slot{hash: hash, index: offset + 1}
+	i.unshare()
+	if i.cuckoo != nil {
+		i.cuckooInsert(s)
+	} else {
+		i.table.slots[cursor] = s
+		if i.growth.target != 0 {
+			l := i.table.len()
+			i.growth.record((cursor-int(i.spread(hash)))&(l-1) + 1)
+		}
+	}
 	i.count++
+	if i.filter != nil {
+		i.filter.add(i.spread(hash))
+	}
+	if i.fold != nil {
+		i.fold.add(i.Get(offset))
+	}
+	if i.dict != nil {
+		i.dict.add(offset)
+	}
+	if i.codes != nil {
+		i.codes.add(offset)
+	}
 
+	for _, fn := range i.onInsert {
+		fn(offset, i.Get(offset))
+	}
+	if i.debug != nil {
+		i.debugSaved("save (new)", val, offset)
+	}
+
+	return offset, nil
+}
+
+// OnInsert registers fn to be called whenever a new string is stored. It is not called for
+// strings that are already present. fn is called synchronously, so must not modify i.
+func (i *Intern) OnInsert(fn func(offset int, s string)) {
+	i.onInsert = append(i.onInsert, fn)
+}
+
+// OnEvict registers fn to be called for each string that leaves i: those dropped by
+// Collect and Evict, and those a compaction doesn't keep. The offset is the one the string
+// had before it was dropped. fn is called synchronously, so must not modify i. Strings
+// that are moved rather than dropped are reported to the moved callback of
+// StartCompaction, or in the map Collect and Evict return.
+func (i *Intern) OnEvict(fn func(offset int, s string)) {
+	i.onEvict = append(i.onEvict, fn)
+}
+
+// evicted calls the OnEvict callbacks for the string at offset, which is being dropped
+func (i *Intern) evicted(offset int) {
+	for _, fn := range i.onEvict {
+		fn(offset, i.Get(offset))
+	}
+}
+
+// Lookup looks for val without storing it. It returns the string's offset and true if it
+// is present, or InvalidOffset and false if not.
+func (i *Intern) Lookup(val string) (offset int, ok bool) {
+	if i.recorder != nil {
+		i.recorder.record(opLookup, val)
+	}
+	offset, ok = i.find(val)
+	if ok && i.arena.meta {
+		i.touch(offset)
+	}
+	if ok && i.compact != nil {
+		i.compact.live.Add(offset)
+	}
+	if ok && i.debug != nil {
+		i.debugSaved("lookup", val, offset)
+	}
+	return offset, ok
+}
+
+// OffsetOf is like Lookup, but returns InvalidOffset alone if val isn't present. It suits
+// filling in a field that holds an offset.
+func (i *Intern) OffsetOf(val string) int {
+	offset, _ := i.Lookup(val)
 	return offset
 }
 
+// TryGet returns the string stored at offset and true, or "" and false if offset is
+// InvalidOffset or any other negative value. Use it to read a field that may hold
+// InvalidOffset.
+func (i *Intern) TryGet(offset int) (string, bool) {
+	if offset < 0 {
+		return "", false
+	}
+	return i.Get(offset), true
+}
+
+// find looks for val without storing it. It returns the string's offset and true if it is
+// present, or InvalidOffset and false if not.
+func (i *Intern) find(val string) (int, bool) {
+	if i.count == 0 {
+		return InvalidOffset, false
+	}
+	hash := i.hash(val)
+	if i.filter != nil && !i.filter.mayContain(i.spread(hash)) {
+		return InvalidOffset, false
+	}
+	var index int
+	if i.cuckoo != nil {
+		index = i.cuckooFind(val, hash)
+	} else {
+		index, _ = i.findLinear(val, hash)
+	}
+	return index - 1, index != 0
+}
+
+// findLinear looks for val in the linear-probing tables. It returns the arena offset of
+// the string + 1, or 0 if it isn't present. In that case cursor is the place in the
+// current table where it should be stored.
+func (i *Intern) findLinear(val string, hash uint64) (index, cursor int) {
+	if i.oldTable.len() != 0 && i.compact == nil {
+		// During a resize every entry in the new table has the same index in the old one
+		if _, index := i.findInTable(i.oldTable, val, hash); index != 0 {
+			return index, 0
+		}
+	}
+	cursor, index = i.findInTable(i.table, val, hash)
+	if index == 0 && i.compact != nil {
+		// During a compaction, strings the cursor hasn't reached yet are only in the
+		// old table
+		if pos, old := i.findInTable(i.oldTable, val, hash); old != 0 && pos >= i.oldTableCursor {
+			index = old
+		}
+	}
+	return index, cursor
+}
+
+// DeduplicateAll returns a slice holding the permanently stored version of each string
+// in vals. It is equivalent to calling Deduplicate on each string in turn, but checks
+// whether the table needs to grow once per batch rather than once per string.
+func (i *Intern) DeduplicateAll(vals []string) []string {
+	out := make([]string, len(vals))
+	copy(out, vals)
+	i.DeduplicateInPlace(out)
+	return out
+}
+
+// DeduplicateInPlace replaces each string in vals with its permanently stored version.
+func (i *Intern) DeduplicateInPlace(vals []string) {
+	for len(vals) > 0 {
+		n := i.makeRoom(len(vals))
+		for j, val := range vals[:n] {
+			if offset, err := i.save(val); err == nil {
+				vals[j] = i.Get(offset)
+			}
+		}
+		vals = vals[n:]
+	}
+}
+
+// SaveAll saves each string in vals and appends the resulting offsets to dst, returning
+// the extended slice. Pass a reused buffer as dst to avoid allocating. Like Save, SaveAll
+// panics if a string can't be stored within the memory budget.
+func (i *Intern) SaveAll(vals []string, dst []int) []int {
+	dst = slices.Grow(dst, len(vals))
+	for len(vals) > 0 {
+		n := i.makeRoom(len(vals))
+		for _, val := range vals[:n] {
+			offset, err := i.save(val)
+			if err != nil {
+				panic(err)
+			}
+			dst = append(dst, offset)
+		}
+		vals = vals[n:]
+	}
+	return dst
+}
+
+// Import copies every string held in src into i. It returns a table that maps each
+// offset in src to the offset of the same string in i, so that callers holding offsets
+// from src can translate them. If a string can't be stored, because of WithMaxBytes or
+// WithMaxEntries, Import stops and returns the error, along with the table for the
+// strings copied so far.
+func (i *Intern) Import(src *Intern) (map[int]int, error) {
+	remap := make(map[int]int, src.count)
+	for offset, val := range src.All() {
+		moved, err := i.TrySave(val)
+		if err != nil {
+			return remap, err
+		}
+		remap[offset] = moved
+	}
+	return remap, nil
+}
+
+// each calls fn with the arena offset of every stored string. Entries are visited
+// once each, even while a resize is in progress
+func (i *Intern) each(fn func(offset int)) {
+	i.eachSlot(func(s slot) {
+		fn(s.index - 1)
+	})
+}
+
+// eachSlot is like each, but passes fn the whole table entry for each string
+func (i *Intern) eachSlot(fn func(s slot)) {
+	if i.cuckoo != nil {
+		for _, s := range i.cuckoo.slots {
+			if s.index != 0 {
+				fn(s)
+			}
+		}
+		return
+	}
+	for _, s := range i.table.slots {
+		if s.index != 0 {
+			fn(s)
+		}
+	}
+	if i.oldTable.len() != 0 {
+		// Entries before the cursor have already been copied into the new table
+		for _, s := range i.oldTable.slots[i.oldTableCursor:] {
+			if s.index != 0 {
+				fn(s)
+			}
+		}
+	}
+}
+
 // findInTable find the string val in the hash table. If the string is present, it returns the
-// place in the table where it was found, plus the stringbank offset of the string + 1
-func (i *Intern) findInTable(table table, val string, hashVal uint32) (cursor int, index int) {
+// place in the table where it was found, plus the arena offset of the string + 1
+func (i *Intern) findInTable(table table, val string, hashVal uint64) (cursor int, index int) {
 	l := table.len()
-	cursor = int(hashVal) & (l - 1)
+	cursor = int(i.spread(hashVal)) & (l - 1)
 	start := cursor
-	for table.indices[cursor] != 0 {
-		if table.hashes[cursor] == hashVal {
-			if index := int(table.indices[cursor]); i.Get(index-1) == val {
-				return cursor, index
+	for table.slots[cursor].index != 0 {
+		if s := table.slots[cursor]; s.hash == hashVal {
+			// Short strings are held in full in the hash, so there's no need to check
+			if isInline(hashVal) || i.Get(s.index-1) == val {
+				if i.probes.sampleEvery != 0 {
+					i.probes.record((cursor-start)&(l-1) + 1)
+				}
+				return cursor, s.index
 			}
 		}
 		cursor++
@@ -113,14 +478,17 @@ func (i *Intern) findInTable(table table, val string, hashVal uint32) (cursor in
 			panic("out of space!")
 		}
 	}
+	if i.probes.sampleEvery != 0 {
+		i.probes.record((cursor-start)&(l-1) + 1)
+	}
 	return cursor, 0
 }
 
-func (i *Intern) copyEntryToTable(table table, index int, hash uint32) {
+func (i *Intern) copyEntryToTable(table table, index int, hash uint64) {
 	l := table.len()
-	cursor := int(hash) & (l - 1)
+	cursor := int(i.spread(hash)) & (l - 1)
 	start := cursor
-	for table.indices[cursor] != 0 {
+	for table.slots[cursor].index != 0 {
 		// the entry we're copying in is guaranteed not to be already
 		// present, so we're just looking for an empty space
 		cursor++
@@ -131,34 +499,83 @@ func (i *Intern) copyEntryToTable(table table, index int, hash uint32) {
 			panic("out of space (resize)!")
 		}
 	}
-	table.indices[cursor] = index
-	table.hashes[cursor] = hash
+	table.slots[cursor] = slot{hash: hash, index: index}
 }
 
 func (i *Intern) resize() {
-	if i.table.hashes == nil {
-		i.table.hashes = make([]uint32, 16)
-		i.table.indices = make([]int, 16)
+	if i.cuckoo != nil {
+		// The cuckoo table grows all at once when an insert needs it to
+		return
+	}
+	if i.table.slots == nil {
+		i.table = newTable(16, i.hugePages)
+	}
+
+	if i.compact != nil {
+		i.compactRoom()
+		if !i.deferMigration {
+			i.migrate()
+		}
+		return
 	}
 
-	if i.count < i.table.len()*3/4 && i.oldTable.len() == 0 {
+	if i.count < i.maxEntries() && i.oldTable.len() == 0 {
 		return
 	}
 
-	if i.oldTable.hashes == nil {
-		i.oldTable, i.table = i.table, table{
-			hashes:  make([]uint32, len(i.table.hashes)*2),
-			indices: make([]int, len(i.table.indices)*2),
+	if i.oldTable.slots == nil {
+		if !i.canGrow() {
+			return
+		}
+		r := traceRegion("intern.grow")
+		i.oldTable, i.table = i.table, newTable(i.table.len()*2, i.hugePages)
+		// The old table is only read from now on, so a Snapshot can keep sharing it
+		i.shared = false
+		if i.filter != nil {
+			// The filter is sized for the largest table, so we rebuild it as that grows
+			i.rebuildFilter()
 		}
+		r.End()
 	}
 
+	if i.deferMigration {
+		if i.count < i.maxEntries() {
+			return
+		}
+		// The new table is filling up before the old one has been copied into it, so
+		// finish the job now
+		i.finishMigration()
+		return
+	}
+	i.migrate()
+}
+
+// finishMigration completes any resize or compaction in progress
+func (i *Intern) finishMigration() {
+	if i.oldTable.len() == 0 {
+		return
+	}
+	defer traceRegion("intern.finishMigration").End()
+	for i.oldTable.len() != 0 {
+		i.migrate()
+	}
+}
+
+// migrate moves the next few entries from the old table to the new one while a resize
+// is in progress.
+func (i *Intern) migrate() {
 	// We copy items between tables 16 at a time. Since we do this every time
 	// anyone writes to the table we won't run out of space in the new table
 	// before this is complete
+	i.unshare()
 	l := i.oldTable.len()
 	for k := 0; k < 16; k++ {
-		if index := i.oldTable.indices[k+i.oldTableCursor]; index != 0 {
-			i.copyEntryToTable(i.table, index, i.oldTable.hashes[k+i.oldTableCursor])
+		if s := i.oldTable.slots[k+i.oldTableCursor]; s.index != 0 {
+			if i.compact != nil {
+				i.relocate(s)
+				continue
+			}
+			i.copyEntryToTable(i.table, s.index, s.hash)
 			// The entry can exist in the old and new versions of the table without
 			// problems. If we did try to delete from the old table we'd have issues
 			// searching forward from clashing entries.
@@ -166,23 +583,210 @@ func (i *Intern) resize() {
 	}
 	i.oldTableCursor += 16
 	if i.oldTableCursor >= l {
-		i.oldTable.hashes = nil
-		i.oldTable.indices = nil
+		i.oldTable = table{}
 		i.oldTableCursor = 0
+		if i.compact != nil {
+			i.finishCompaction()
+		}
+		if i.debug != nil {
+			i.debugCheck("resize or compaction complete")
+		}
+	}
+}
+
+// Reserve grows the table so that n more strings can be stored without any resize work,
+// completing any resize already in progress. Call it before a known burst of new strings
+// to keep the latency of each insert steady. The table is not grown if that would exceed
+// the memory budget.
+func (i *Intern) Reserve(n int) {
+	defer traceRegion("intern.reserve").End()
+	if i.debug != nil {
+		defer i.debugCheck("reserve")
+	}
+	if i.cuckoo == nil {
+		i.resize()
+		i.finishMigration()
+	}
+
+	if i.entryLimit != 0 {
+		n = min(n, i.entryLimit-i.count)
+	}
+	l := max(i.Cap(), 16)
+	for l*i.loadLimit()/16 < i.count+n {
+		l *= 2
+	}
+	if l == i.Cap() {
+		return
+	}
+	if i.maxBytes != 0 && i.memory()+l*int(unsafe.Sizeof(slot{})) > i.maxBytes {
+		return
+	}
+
+	if i.cuckoo != nil {
+		i.cuckooRebuild(l, i.cuckooEntries(slot{})[:i.count])
+		return
+	}
+	t := newTable(l, i.hugePages)
+	for _, s := range i.table.slots {
+		if s.index != 0 {
+			i.copyEntryToTable(t, s.index, s.hash)
+		}
+	}
+	i.table = t
+	i.shared = false
+	if i.filter != nil {
+		i.rebuildFilter()
+	}
+}
+
+// makeRoom prepares the table for a batch of up to n new strings. It completes any
+// resize that is in progress, growing the table first if it is full, and returns how many
+// strings can then be stored with save before the table needs to grow again.
+func (i *Intern) makeRoom(n int) int {
+	if i.cuckoo != nil {
+		return n
+	}
+	for {
+		i.resize()
+		i.finishMigration()
+		if room := i.maxEntries() - i.count; room > 0 {
+			return min(n, room)
+		}
+		if !i.canGrow() {
+			// save will refuse to add any new strings, so the whole batch can go
+			return n
+		}
+	}
+}
+
+// maxEntries returns how many strings the table can hold before it needs to grow
+func (i *Intern) maxEntries() int {
+	return i.Cap() * i.loadLimit() / 16
+}
+
+// loadLimit returns the load factor at which the table grows, in sixteenths
+func (i *Intern) loadLimit() int {
+	if i.cuckoo != nil {
+		return cuckooLoadLimit
+	}
+	return i.growth.loadLimit()
+}
+
+// memory returns the number of bytes used by the tables and the string arena. It
+// returns 0 if i is nil
+func (i *Intern) memory() int {
+	if i == nil {
+		return 0
+	}
+	return i.MemoryUsage().Total()
+}
+
+// canGrow reports whether the table may double in size without exceeding the memory
+// budget
+func (i *Intern) canGrow() bool {
+	if i.entryLimit != 0 && i.maxEntries() >= i.entryLimit {
+		// There's already room for as many strings as we're allowed
+		return false
 	}
+	return i.maxBytes == 0 || i.memory()+2*(i.table.bytes()+i.cuckoo.bytes()) <= i.maxBytes
 }
 
-// table represents a hash table. We keep the indices and hashes separate in
-// case we want to use different size types in the future
+// checkBudgetPair is checkBudget for storing k and then v, both of which are new. It
+// passes only if there is room for both, so that storing k can't leave too little room
+// for v.
+func (i *Intern) checkBudgetPair(k, v string) error {
+	if i.entryLimit != 0 && i.count+2 > i.entryLimit {
+		return ErrFull
+	}
+	growth, offset := i.arena.growthPair(len(k), len(v))
+	size := i.arena.chunkSize()
+	if growth != 0 && (max(len(k), len(v)) >= math.MaxInt-size || len(i.arena.chunks)+growth/size > math.MaxInt/size) {
+		return ErrTooLarge
+	}
+	if i.codes != nil && (!i.codes.canAdd(offset) || uint64(len(i.codes.offsets))+1 >= math.MaxUint32) {
+		return ErrTooLarge
+	}
+	if i.maxBytes == 0 {
+		return nil
+	}
+	memory := i.memory() + growth
+	// As in canGrow, there's no need to grow once there's room for as many strings as
+	// we're allowed
+	roomy := i.entryLimit != 0 && i.maxEntries() >= i.entryLimit
+	if !roomy && i.count+1 >= i.maxEntries() && (i.cuckoo != nil || i.oldTable.len() == 0) {
+		// The table must grow to take v once k is stored
+		memory += 2 * (i.table.bytes() + i.cuckoo.bytes())
+	}
+	if memory > i.maxBytes {
+		return ErrMaxBytes
+	}
+	return nil
+}
+
+// checkBudget returns ErrMaxBytes if storing the new string val would take us over
+// the memory budget. The arena allocates memory a chunk at a time, so a string that
+// doesn't fit in the current chunk costs a whole new one.
+func (i *Intern) checkBudget(val string) error {
+	if i.entryLimit != 0 && i.count >= i.entryLimit {
+		return ErrFull
+	}
+	if i.arena.growth(len(val)) != 0 && !i.arena.canGrow(len(val)) {
+		return ErrTooLarge
+	}
+	if i.codes != nil && !i.codes.canAdd(i.arena.nextOffset(len(val))) {
+		return ErrTooLarge
+	}
+	if i.maxBytes == 0 {
+		return nil
+	}
+	if i.cuckoo != nil {
+		if i.count >= i.maxEntries() && !i.canGrow() {
+			// The table would have to grow to hold another string
+			return ErrMaxBytes
+		}
+	} else if i.oldTable.len() == 0 && i.count >= i.maxEntries() {
+		// The table is full and was not allowed to grow
+		return ErrMaxBytes
+	}
+	if i.memory()+i.arena.growth(len(val)) > i.maxBytes {
+		return ErrMaxBytes
+	}
+	return nil
+}
+
+// table represents a hash table. Each slot holds a hash next to its index, so that
+// probing a slot touches a single cache line. Four slots fit in a 64-byte line, so a
+// short run of linear probes usually stays within one or two lines.
 type table struct {
+	slots []slot
+}
+
+type slot struct {
 	// We keep hashes in the table to speed up resizing, and also stepping through
-	// entries that have different hashes but hit the same bucket
-	hashes []uint32
-	// index is the index of the string in the stringbank, plus 1 so that valid
+	// entries that have different hashes but hit the same bucket. We keep all 64 bits
+	// so that even in very large tables it is rare for different strings to have equal
+	// hashes, which would cost a string comparison. The top byte holds the string's
+	// length rather than part of the hash, as that is just as good at ruling out
+	// mismatches. Strings of fewer than 8 bytes are stored in full instead of a hash;
+	// see Intern.hash
+	hash uint64
+	// Slots are 16 bytes where int has 32 bits too, so that four still share a cache
+	// line. The padding goes before index, as a zero-sized last field takes up space
+	_ [8 - unsafe.Sizeof(int(0))]byte
+	// index is the index of the string in the arena, plus 1 so that valid
 	// entries are never zero
-	indices []int
+	index int
+}
+
+func newTable(l int, huge bool) table {
+	return table{slots: allocSlots(l, huge)}
 }
 
 func (t table) len() int {
-	return len(t.hashes)
+	return len(t.slots)
+}
+
+// bytes returns the memory used by the table
+func (t table) bytes() int {
+	return len(t.slots) * int(unsafe.Sizeof(slot{}))
 }
@@ -2,6 +2,7 @@ package intern_test
 
 import (
 	"reflect"
+	"slices"
 	"strconv"
 	"testing"
 	"unsafe"
@@ -84,6 +85,169 @@ func TestResize(t *testing.T) {
 	}
 }
 
+func TestDeduplicateAll(t *testing.T) {
+	in := &intern.Intern{}
+
+	vals := make([]string, 1000)
+	for j := range vals {
+		vals[j] = strconv.Itoa(j % 300)
+	}
+
+	out := in.DeduplicateAll(vals)
+	assert.Equal(t, vals, out)
+	assert.Equal(t, 300, in.Len())
+	for j, val := range out {
+		assert.Equal(t, datapointer(in.Deduplicate(vals[j])), datapointer(val))
+	}
+
+	in.DeduplicateInPlace(vals)
+	assert.Equal(t, out, vals)
+	assert.Equal(t, 300, in.Len())
+	for j := range vals {
+		assert.Equal(t, datapointer(out[j]), datapointer(vals[j]))
+	}
+}
+
+func TestSaveAll(t *testing.T) {
+	in := intern.New(16)
+
+	vals := make([]string, 1000)
+	for j := range vals {
+		vals[j] = strconv.Itoa(j % 300)
+	}
+
+	buf := []int{-1}
+	offsets := in.SaveAll(vals, buf)
+	assert.Len(t, offsets, 1001)
+	assert.Equal(t, -1, offsets[0])
+	for j, val := range vals {
+		assert.Equal(t, in.Save(val), offsets[j+1])
+	}
+	assert.Equal(t, 300, in.Len())
+}
+
+func TestAppendGet(t *testing.T) {
+	in := intern.New(16)
+	hat := in.Save("hat")
+	sat := in.Save("sat")
+
+	buf := make([]byte, 0, 16)
+	buf = in.AppendGet(buf, hat)
+	buf = append(buf, ' ')
+	buf = in.AppendGet(buf, sat)
+	assert.Equal(t, "hat sat", string(buf))
+}
+
+func TestGetBytes(t *testing.T) {
+	in := intern.New(16)
+	hat := in.Save("hat")
+	empty := in.Save("")
+
+	b := in.GetBytes(hat)
+	assert.Equal(t, []byte("hat"), b)
+	assert.Equal(t, 3, cap(b))
+	assert.Equal(t, datapointer(in.Get(hat)), uintptr(unsafe.Pointer(&b[0])))
+
+	assert.Len(t, in.GetBytes(empty), 0)
+}
+
+func TestOnEvict(t *testing.T) {
+	in := intern.New(16)
+	evicted := make(map[int]string)
+	in.OnEvict(func(offset int, s string) {
+		evicted[offset] = s
+	})
+
+	// Collect
+	hat := in.Save("hat")
+	sat := in.Save("sat")
+	remap := in.Collect(slices.Values([]int{sat}))
+	assert.Equal(t, map[int]string{hat: "hat"}, evicted)
+	sat = remap[sat]
+
+	// Evict
+	clear(evicted)
+	mat := in.Save("mat")
+	remap = in.Evict(1)
+	assert.Equal(t, map[int]string{sat: "sat"}, evicted)
+	mat = remap[mat]
+
+	// Compaction
+	clear(evicted)
+	var offsets []int
+	for j := range 100 {
+		offsets = append(offsets, in.Save(strconv.Itoa(j)))
+	}
+	in.StartCompaction(slices.Values(offsets), nil)
+	for !in.CompactStep(16) {
+	}
+	assert.Equal(t, map[int]string{mat: "mat"}, evicted)
+	assert.Equal(t, 100, in.Len())
+}
+
+func TestOnInsert(t *testing.T) {
+	in := intern.New(16)
+	replica := intern.New(16)
+
+	inserted := make(map[int]string)
+	in.OnInsert(func(offset int, s string) {
+		inserted[offset] = s
+	})
+	in.OnInsert(func(offset int, s string) {
+		replica.Save(s)
+	})
+
+	hat := in.Save("hat")
+	in.Save("hat")
+	sat := in.Deduplicate("sat")
+	in.DeduplicateAll([]string{"sat", "mat"})
+
+	assert.Len(t, inserted, 3)
+	assert.Equal(t, "hat", inserted[hat])
+	assert.Equal(t, datapointer(sat), datapointer(inserted[in.Save("sat")]))
+	assert.Equal(t, 3, replica.Len())
+}
+
+func TestImport(t *testing.T) {
+	src := intern.New(16)
+	dst := intern.New(16)
+
+	for j := 0; j < 100; j++ {
+		dst.Save(strconv.Itoa(j * 2))
+	}
+
+	offsets := make(map[string]int)
+	for j := 0; j < 100; j++ {
+		val := strconv.Itoa(j)
+		offsets[val] = src.Save(val)
+	}
+
+	remap, err := dst.Import(src)
+	assert.NoError(t, err)
+	assert.Len(t, remap, 100)
+	assert.Equal(t, 150, dst.Len())
+
+	for val, offset := range offsets {
+		assert.Equal(t, val, dst.Get(remap[offset]))
+	}
+}
+
+func TestImportMaxEntries(t *testing.T) {
+	src := intern.New(16)
+	for j := 0; j < 100; j++ {
+		src.Save(strconv.Itoa(j))
+	}
+
+	dst := intern.New(16, intern.WithMaxEntries(60))
+	remap, err := dst.Import(src)
+	assert.Equal(t, intern.ErrFull, err)
+	assert.Len(t, remap, 60)
+	assert.Equal(t, 60, dst.Len())
+	for offset, moved := range remap {
+		assert.Equal(t, src.Get(offset), dst.Get(moved))
+	}
+}
+
 func BenchmarkIntern(b *testing.B) {
 	s := make([]string, b.N)
 	for i := range s {
@@ -105,6 +269,24 @@ func BenchmarkIntern(b *testing.B) {
 	}
 }
 
+func BenchmarkDeduplicateAll(b *testing.B) {
+	s := make([]string, b.N)
+	for i := range s {
+		s[i] = strconv.Itoa(i)
+	}
+
+	intern := intern.New(16)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	intern.DeduplicateInPlace(s)
+
+	if s[b.N-1] != strconv.Itoa(b.N-1) {
+		b.Errorf("last dedupe not as expected. Have %s expected %d", s[b.N-1], b.N-1)
+	}
+}
+
 func BenchmarkInternBasic(b *testing.B) {
 	s := make([]string, b.N)
 	for i := range s {
@@ -130,3 +312,132 @@ func BenchmarkInternBasic(b *testing.B) {
 		b.Errorf("last dedupe not as expected. Have %s expected %d", dedupe, b.N-1)
 	}
 }
+
+func TestDeferredMigration(t *testing.T) {
+	// With nothing to copy entries in the background, the writer copies them all once
+	// the new table fills
+	in := intern.New(16, intern.WithBackgroundResize())
+	offsets := make([]int, 10000)
+	for j := range offsets {
+		offsets[j] = in.Save(strconv.Itoa(j))
+	}
+	assert.Equal(t, 10000, in.Len())
+	for j, offset := range offsets {
+		assert.Equal(t, offset, in.Save(strconv.Itoa(j)))
+		assert.Equal(t, strconv.Itoa(j), in.Get(offset))
+	}
+}
+
+func TestReserve(t *testing.T) {
+	for _, opts := range [][]intern.Option{nil, {intern.WithCuckoo()}, {intern.WithBloomFilter()}} {
+		in := intern.New(16, opts...)
+		for j := 0; j < 100; j++ {
+			in.Save(strconv.Itoa(j))
+		}
+		in.Reserve(10000)
+		cap := in.Cap()
+		assert.True(t, cap >= 10100, cap)
+
+		for j := 100; j < 10100; j++ {
+			in.Save(strconv.Itoa(j))
+		}
+		assert.Equal(t, cap, in.Cap())
+		assert.Equal(t, 10100, in.Len())
+		for j := 0; j < 10100; j++ {
+			_, ok := in.Lookup(strconv.Itoa(j))
+			assert.True(t, ok)
+		}
+
+		// There's already room
+		in.Reserve(1)
+		assert.Equal(t, cap, in.Cap())
+	}
+}
+
+func TestReserveMaxBytes(t *testing.T) {
+	in := intern.New(16, intern.WithMaxBytes(1<<20))
+	in.Reserve(1 << 20)
+	assert.Equal(t, 16, in.Cap())
+}
+
+func TestTryDeduplicate(t *testing.T) {
+	in := intern.New(16, intern.WithMaxEntries(1))
+	hat, err := in.TryDeduplicate("hat")
+	assert.NoError(t, err)
+	assert.Equal(t, "hat", hat)
+
+	again, err := in.TryDeduplicate("hat")
+	assert.NoError(t, err)
+	assert.Equal(t, datapointer(hat), datapointer(again))
+
+	sat := "sat"
+	got, err := in.TryDeduplicate(sat)
+	assert.Equal(t, intern.ErrFull, err)
+	assert.Equal(t, datapointer(sat), datapointer(got))
+
+	in = intern.New(16, intern.WithMaxBytes(1))
+	_, err = in.TryDeduplicate(sat)
+	assert.Equal(t, intern.ErrMaxBytes, err)
+}
+
+func TestInvalidOffset(t *testing.T) {
+	for _, opts := range [][]intern.Option{nil, {intern.WithBloomFilter()}, {intern.WithCuckoo()}} {
+		in := intern.New(16, opts...)
+		offset, ok := in.Lookup("hat")
+		assert.False(t, ok)
+		assert.Equal(t, intern.InvalidOffset, offset)
+
+		// The first string saved has offset 0, so 0 can't mean "no string"
+		assert.Equal(t, 0, in.Save("hat"))
+		assert.Equal(t, 0, in.OffsetOf("hat"))
+		assert.Equal(t, intern.InvalidOffset, in.OffsetOf("scarf"))
+		offset, ok = in.Snapshot().Lookup("scarf")
+		assert.False(t, ok)
+		assert.Equal(t, intern.InvalidOffset, offset)
+
+		val, ok := in.TryGet(0)
+		assert.True(t, ok)
+		assert.Equal(t, "hat", val)
+		val, ok = in.TryGet(intern.InvalidOffset)
+		assert.False(t, ok)
+		assert.Equal(t, "", val)
+	}
+
+	s := intern.NewStriped(16, 8)
+	offset, ok := s.Lookup("hat")
+	assert.False(t, ok)
+	assert.Equal(t, intern.InvalidOffset, offset)
+}
+
+func TestBytes(t *testing.T) {
+	in := intern.New(16)
+	hat := in.Save("hat")
+
+	b := []byte("hat")
+	assert.Equal(t, hat, in.OffsetForBytes(b))
+	scarf := in.OffsetForBytes([]byte("scarf"))
+	assert.Equal(t, "scarf", in.Get(scarf))
+
+	// The stored strings don't share b's memory
+	b = []byte("glove")
+	val := in.DeduplicateBytes(b)
+	b[0] = 'X'
+	assert.Equal(t, "glove", val)
+	assert.Equal(t, in.Deduplicate("glove"), val)
+
+	n := testing.AllocsPerRun(100, func() {
+		in.OffsetForBytes(b)
+		in.DeduplicateBytes(b)
+	})
+	assert.Zero(t, n)
+}
+
+func TestBytesFull(t *testing.T) {
+	in := intern.New(16, intern.WithMaxEntries(1))
+	in.Save("hat")
+	b := []byte("scarf")
+	val := in.DeduplicateBytes(b)
+	b[0] = 'X'
+	assert.Equal(t, "scarf", val)
+	assert.Panics(t, func() { in.OffsetForBytes(b) })
+}
@@ -0,0 +1,87 @@
+package intern
+
+import (
+	"iter"
+	"slices"
+	"strings"
+)
+
+// All returns an iterator over every stored string and its offset. The order is
+// unspecified.
+func (i *Intern) All() iter.Seq2[int, string] {
+	return func(yield func(int, string) bool) {
+		for _, offset := range i.offsets() {
+			if !yield(offset, i.Get(offset)) {
+				return
+			}
+		}
+	}
+}
+
+// AllInOrder returns an iterator over every stored string and its offset, in offset
+// order. The arena hands out offsets in increasing order, so this is the order the
+// strings were first saved, as long as StartCompaction hasn't moved them. A compaction
+// copies strings to new offsets in an order of its own, and AllInOrder follows that
+// order afterwards.
+func (i *Intern) AllInOrder() iter.Seq2[int, string] {
+	return func(yield func(int, string) bool) {
+		offsets := i.offsets()
+		slices.Sort(offsets)
+		for _, offset := range offsets {
+			if !yield(offset, i.Get(offset)) {
+				return
+			}
+		}
+	}
+}
+
+// AllSorted returns an iterator over every stored string and its offset, in
+// lexicographic order of the strings, or in the order of the collator set with
+// WithCollator. Only the offsets are copied and sorted; the strings themselves are read
+// from the arena as they are compared and yielded.
+func (i *Intern) AllSorted() iter.Seq2[int, string] {
+	return func(yield func(int, string) bool) {
+		offsets := i.offsets()
+		slices.SortFunc(offsets, i.CompareOffsets)
+		for _, offset := range offsets {
+			if !yield(offset, i.Get(offset)) {
+				return
+			}
+		}
+	}
+}
+
+// CompareOffsets compares the strings stored at offsets a and b lexicographically, or
+// with the collator set with WithCollator, returning -1, 0 or +1 as strings.Compare
+// does. The strings are compared where they lie in the arena, so it can be passed to
+// slices.SortFunc to order offsets by their strings without anything being copied.
+func (i *Intern) CompareOffsets(a, b int) int {
+	if i.collator != nil {
+		return i.collator.CompareString(i.Get(a), i.Get(b))
+	}
+	return strings.Compare(i.Get(a), i.Get(b))
+}
+
+// WithPrefix returns an iterator over the stored strings that start with prefix, along
+// with their offsets. The order is unspecified. This is a linear scan of the table.
+func (i *Intern) WithPrefix(prefix string) iter.Seq2[int, string] {
+	return func(yield func(int, string) bool) {
+		for _, offset := range i.offsets() {
+			if val := i.Get(offset); strings.HasPrefix(val, prefix) {
+				if !yield(offset, val) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// offsets returns the offsets of all the stored strings. We take a copy so callers can
+// carry on saving strings while iterating.
+func (i *Intern) offsets() []int {
+	offsets := make([]int, 0, i.count)
+	i.each(func(offset int) {
+		offsets = append(offsets, offset)
+	})
+	return offsets
+}
@@ -0,0 +1,97 @@
+package intern_test
+
+import (
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAll(t *testing.T) {
+	in := intern.New(16)
+	for j := 0; j < 1000; j++ {
+		in.Save(strconv.Itoa(j))
+	}
+
+	seen := make(map[string]bool)
+	for offset, val := range in.All() {
+		assert.Equal(t, val, in.Get(offset))
+		assert.False(t, seen[val], val)
+		seen[val] = true
+	}
+	assert.Len(t, seen, 1000)
+}
+
+func TestAllInOrder(t *testing.T) {
+	in := intern.New(16)
+	for j := 999; j >= 0; j-- {
+		in.Save(strconv.Itoa(j))
+		in.Save(strconv.Itoa(j))
+	}
+
+	var vals []string
+	for offset, val := range in.AllInOrder() {
+		assert.Equal(t, val, in.Get(offset))
+		vals = append(vals, val)
+	}
+	assert.Len(t, vals, 1000)
+	for j, val := range vals {
+		assert.Equal(t, strconv.Itoa(999-j), val)
+	}
+}
+
+func TestAllSorted(t *testing.T) {
+	in := intern.New(16)
+	var expected []string
+	for j := 0; j < 1000; j++ {
+		val := strconv.Itoa(j)
+		in.Save(val)
+		expected = append(expected, val)
+	}
+	sort.Strings(expected)
+
+	var vals []string
+	for offset, val := range in.AllSorted() {
+		assert.Equal(t, val, in.Get(offset))
+		vals = append(vals, val)
+	}
+	assert.Equal(t, expected, vals)
+}
+
+func TestWithPrefix(t *testing.T) {
+	in := intern.New(16)
+	for _, val := range []string{"host", "hostname", "ho", "path", "hostile", ""} {
+		in.Save(val)
+	}
+
+	var vals []string
+	for offset, val := range in.WithPrefix("host") {
+		assert.Equal(t, val, in.Get(offset))
+		vals = append(vals, val)
+	}
+	assert.ElementsMatch(t, []string{"host", "hostname", "hostile"}, vals)
+
+	var count int
+	for range in.WithPrefix("") {
+		count++
+	}
+	assert.Equal(t, 6, count)
+}
+
+func TestAllStop(t *testing.T) {
+	in := intern.New(16)
+	for j := 0; j < 100; j++ {
+		in.Save(strconv.Itoa(j))
+	}
+
+	var count int
+	for range in.AllInOrder() {
+		count++
+		if count == 10 {
+			break
+		}
+	}
+	assert.Equal(t, 10, count)
+}
@@ -0,0 +1,67 @@
+//go:build goexperiment.jsonv2 && go1.27
+
+package intern
+
+import (
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+)
+
+// ReadJSONString reads the next value from d, which must be a JSON string, and returns
+// it interned. The raw value is unquoted into a reused buffer, so a string already stored
+// costs no allocation at all.
+func (i *Intern) ReadJSONString(d *jsontext.Decoder) (string, error) {
+	if k := d.PeekKind(); k != '"' {
+		if k == 0 {
+			// PeekKind hides the error, but reading brings it out
+			_, err := d.ReadValue()
+			return "", err
+		}
+		return "", fmt.Errorf("intern: expected a JSON string, found %v", k)
+	}
+	v, err := d.ReadValue()
+	if err != nil {
+		return "", err
+	}
+	if i.scratch, err = jsontext.AppendUnquote(i.scratch[:0], v); err != nil {
+		return "", err
+	}
+	return i.DeduplicateBytes(i.scratch), nil
+}
+
+// ReadJSONToken is like d.ReadToken, but interns the strings in string tokens, including
+// object member names. Other tokens are returned as they are.
+func (i *Intern) ReadJSONToken(d *jsontext.Decoder) (jsontext.Token, error) {
+	if d.PeekKind() != '"' {
+		return d.ReadToken()
+	}
+	val, err := i.ReadJSONString(d)
+	if err != nil {
+		return jsontext.Token{}, err
+	}
+	return jsontext.String(val), nil
+}
+
+// JSONUnmarshalers returns unmarshalers that intern every JSON string decoded into a Go
+// string, for use with json.WithUnmarshalers:
+//
+//	err := json.Unmarshal(data, &v, json.WithUnmarshalers(in.JSONUnmarshalers()))
+//
+// Map keys are decoded as usual, without interning. The Intern must not be used by
+// anything else while unmarshaling is in progress.
+func (i *Intern) JSONUnmarshalers() *json.Unmarshalers {
+	return json.UnmarshalFromFunc(func(d *jsontext.Decoder, val *string) error {
+		if d.PeekKind() != '"' {
+			// Let json deal with null and with reporting other kinds of value
+			return errors.ErrUnsupported
+		}
+		s, err := i.ReadJSONString(d)
+		if err != nil {
+			return err
+		}
+		*val = s
+		return nil
+	})
+}
@@ -0,0 +1,68 @@
+//go:build goexperiment.jsonv2 && go1.27
+
+package intern_test
+
+import (
+	"encoding/json/jsontext"
+	"encoding/json/v2"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadJSONToken(t *testing.T) {
+	in := intern.New(16)
+	d := jsontext.NewDecoder(strings.NewReader(`{"name":"hat","tags":["wool","h\u0061t"],"size":3}`))
+
+	var strs []string
+	for {
+		tok, err := in.ReadJSONToken(d)
+		if err != nil {
+			break
+		}
+		if tok.Kind() == '"' {
+			strs = append(strs, tok.String())
+		}
+	}
+	assert.Equal(t, []string{"name", "hat", "tags", "wool", "hat", "size"}, strs)
+	assert.Equal(t, 5, in.Len())
+	// Both copies of "hat" share the stored bytes, escaped or not
+	assert.Equal(t, unsafe.StringData(strs[1]), unsafe.StringData(strs[4]))
+}
+
+func TestReadJSONString(t *testing.T) {
+	in := intern.New(16)
+	d := jsontext.NewDecoder(strings.NewReader(`"hat" 3`))
+	val, err := in.ReadJSONString(d)
+	assert.NoError(t, err)
+	assert.Equal(t, "hat", val)
+
+	_, err = in.ReadJSONString(d)
+	assert.EqualError(t, err, "intern: expected a JSON string, found number")
+
+	d = jsontext.NewDecoder(strings.NewReader(`"hat`))
+	_, err = in.ReadJSONString(d)
+	assert.Error(t, err)
+}
+
+func TestJSONUnmarshalers(t *testing.T) {
+	type item struct {
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Extra *string  `json:"extra"`
+	}
+	in := intern.New(16)
+	var items []item
+	err := json.Unmarshal([]byte(`[{"name":"hat","tags":["wool"],"extra":null},{"name":"hat","tags":["wool","red"]}]`), &items,
+		json.WithUnmarshalers(in.JSONUnmarshalers()))
+	assert.NoError(t, err)
+	assert.Equal(t, []item{{Name: "hat", Tags: []string{"wool"}}, {Name: "hat", Tags: []string{"wool", "red"}}}, items)
+	assert.Equal(t, 3, in.Len())
+	assert.Equal(t, unsafe.StringData(items[0].Name), unsafe.StringData(items[1].Name))
+
+	err = json.Unmarshal([]byte(`{"name":3}`), &item{}, json.WithUnmarshalers(in.JSONUnmarshalers()))
+	assert.Error(t, err)
+}
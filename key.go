@@ -0,0 +1,42 @@
+package intern
+
+import "encoding/binary"
+
+// SaveKey stores a composite key made of parts and returns its offset, saving the need to
+// join the parts with fmt.Sprintf or a separator first. The parts are stored together as
+// a single string, each preceded by its length, so no choice of separator can make two
+// different keys collide. Get returns that encoded form, and KeyParts splits it back up.
+// SaveKey panics if the key can't be stored within the memory budget.
+func (i *Intern) SaveKey(parts ...string) int {
+	offset, err := i.TrySaveKey(parts...)
+	if err != nil {
+		panic(err)
+	}
+	return offset
+}
+
+// TrySaveKey is like SaveKey, but returns ErrMaxBytes rather than panicking if the key
+// can't be stored.
+func (i *Intern) TrySaveKey(parts ...string) (int, error) {
+	key := i.scratch[:0]
+	for _, part := range parts {
+		key = binary.AppendUvarint(key, uint64(len(part)))
+		key = append(key, part...)
+	}
+	i.scratch = key
+	return i.saveBytes(key)
+}
+
+// KeyParts returns the parts of the composite key stored at offset by SaveKey. The parts
+// share the stored key's memory, so no copies are made.
+func (i *Intern) KeyParts(offset int) []string {
+	key := i.Get(offset)
+	var parts []string
+	for len(key) > 0 {
+		l, n := binary.Uvarint([]byte(key[:min(len(key), binary.MaxVarintLen64)]))
+		key = key[n:]
+		parts = append(parts, key[:l])
+		key = key[l:]
+	}
+	return parts
+}
@@ -0,0 +1,36 @@
+package intern_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveKey(t *testing.T) {
+	var in intern.Intern
+
+	a := in.SaveKey("GET", "/users", "200")
+	assert.Equal(t, a, in.SaveKey("GET", "/users", "200"))
+	assert.Equal(t, []string{"GET", "/users", "200"}, in.KeyParts(a))
+
+	// Keys that would collide if joined with a separator stay apart
+	b := in.SaveKey("a|b", "c")
+	c := in.SaveKey("a", "b|c")
+	assert.NotEqual(t, b, c)
+	assert.Equal(t, []string{"a|b", "c"}, in.KeyParts(b))
+	assert.Equal(t, []string{"a", "b|c"}, in.KeyParts(c))
+
+	d := in.SaveKey("", strings.Repeat("long", 100), "")
+	assert.Equal(t, []string{"", strings.Repeat("long", 100), ""}, in.KeyParts(d))
+	assert.Empty(t, in.KeyParts(in.SaveKey()))
+	assert.Equal(t, 5, in.Len())
+}
+
+func TestSaveKeyMaxBytes(t *testing.T) {
+	in := intern.New(16, intern.WithMaxBytes(1))
+	_, err := in.TrySaveKey("a", "b")
+	assert.Equal(t, intern.ErrMaxBytes, err)
+	assert.Panics(t, func() { in.SaveKey("a", "b") })
+}
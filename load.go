@@ -0,0 +1,92 @@
+package intern
+
+import (
+	"context"
+	"sync"
+)
+
+// loadBatch is the number of strings each LoadFrom worker hashes before handing them on
+const loadBatch = 256
+
+// hashed is a string with its hash
+type hashed struct {
+	val  string
+	hash uint64
+}
+
+// LoadFrom saves every string received from ch until it is closed. The strings are hashed
+// by workers goroutines in parallel, and only the final step of adding them to the table
+// happens on the calling goroutine, so loading a large corpus is limited by how fast it
+// can be read rather than by hashing. LoadFrom returns early with the context's error if
+// ctx is cancelled, or with ErrMaxBytes if a string can't be stored within the memory
+// budget. Strings received before then are stored. The hash function must be safe to call
+// concurrently, as all the built-in ones are.
+func (i *Intern) LoadFrom(ctx context.Context, ch <-chan string, workers int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan []hashed, max(workers, 1))
+	var wg sync.WaitGroup
+	for w := 0; w < max(workers, 1); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			i.hashFrom(ctx, ch, batches)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(batches)
+	}()
+
+	var err error
+	for batch := range batches {
+		if err != nil {
+			// Keep draining so the workers can finish
+			continue
+		}
+		for _, h := range batch {
+			i.resize()
+			if _, err = i.saveHash(h.val, h.hash); err != nil {
+				cancel()
+				break
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// hashFrom reads strings from ch, hashes them and sends them on to out in batches
+func (i *Intern) hashFrom(ctx context.Context, ch <-chan string, out chan<- []hashed) {
+	batch := make([]hashed, 0, loadBatch)
+	send := func() bool {
+		select {
+		case out <- batch:
+			batch = make([]hashed, 0, loadBatch)
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for {
+		select {
+		case val, ok := <-ch:
+			if !ok {
+				if len(batch) > 0 {
+					send()
+				}
+				return
+			}
+			batch = append(batch, hashed{val: val, hash: i.hash(val)})
+			if len(batch) == loadBatch && !send() {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package intern_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFrom(t *testing.T) {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for j := 0; j < 100000; j++ {
+			ch <- strconv.Itoa(j % 50000)
+		}
+	}()
+
+	in := intern.New(16)
+	assert.NoError(t, in.LoadFrom(context.Background(), ch, 4))
+	assert.Equal(t, 50000, in.Len())
+	for j := 0; j < 50000; j++ {
+		_, ok := in.Lookup(strconv.Itoa(j))
+		assert.True(t, ok, j)
+	}
+}
+
+func TestLoadFromCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan string)
+	go func() {
+		for j := 0; j < 1000; j++ {
+			ch <- strconv.Itoa(j)
+		}
+		// Nothing more arrives, so the load only ends when cancelled
+		cancel()
+	}()
+
+	var in intern.Intern
+	assert.Equal(t, context.Canceled, in.LoadFrom(ctx, ch, 2))
+}
+
+func TestLoadFromMaxBytes(t *testing.T) {
+	ch := make(chan string, 100000)
+	for j := 0; j < 100000; j++ {
+		ch <- strconv.Itoa(j)
+	}
+	close(ch)
+
+	in := intern.New(16, intern.WithMaxBytes(300*1024))
+	assert.Equal(t, intern.ErrMaxBytes, in.LoadFrom(context.Background(), ch, 4))
+	assert.True(t, in.Len() > 0 && in.Len() < 100000, in.Len())
+}
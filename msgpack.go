@@ -0,0 +1,95 @@
+package intern
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// ErrMsgpack is returned when msgpack data doesn't hold the string expected
+var ErrMsgpack = errors.New("intern: invalid msgpack string")
+
+// ReadMsgpackString decodes the msgpack string at the start of b, interns it and returns
+// it with the rest of b. It follows the conventions of code generated by
+// github.com/tinylib/msgp, so can be called from hand-written decoding of string
+// fields. The string is looked up straight from b, so nothing is allocated if it is
+// already stored.
+func (i *Intern) ReadMsgpackString(b []byte) (val string, rest []byte, err error) {
+	raw, rest, err := msgpackString(b)
+	if err != nil {
+		return "", b, err
+	}
+	return i.DeduplicateBytes(raw), rest, nil
+}
+
+// msgpackString returns the bytes of the msgpack string at the start of b, and the rest
+// of b
+func msgpackString(b []byte) (raw, rest []byte, err error) {
+	if len(b) == 0 {
+		return nil, b, fmt.Errorf("%w: data is truncated", ErrMsgpack)
+	}
+	var l, n int
+	switch c := b[0]; {
+	case c&0xe0 == 0xa0:
+		// fixstr
+		l, n = int(c&0x1f), 1
+	case c == 0xd9 && len(b) >= 2:
+		l, n = int(b[1]), 2
+	case c == 0xda && len(b) >= 3:
+		l, n = int(binary.BigEndian.Uint16(b[1:])), 3
+	case c == 0xdb && len(b) >= 5:
+		l, n = int(binary.BigEndian.Uint32(b[1:])), 5
+	case c == 0xd9 || c == 0xda || c == 0xdb:
+		return nil, b, fmt.Errorf("%w: data is truncated", ErrMsgpack)
+	default:
+		return nil, b, fmt.Errorf("%w: found type %#x", ErrMsgpack, c)
+	}
+	if l < 0 || len(b)-n < l {
+		return nil, b, fmt.Errorf("%w: data is truncated", ErrMsgpack)
+	}
+	return b[n : n+l], b[n+l:], nil
+}
+
+// AppendMsgpackString appends val to dst as a msgpack string, using the shortest
+// encoding
+func AppendMsgpackString(dst []byte, val string) []byte {
+	switch l := len(val); {
+	case l < 32:
+		dst = append(dst, 0xa0|byte(l))
+	case l < 1<<8:
+		dst = append(dst, 0xd9, byte(l))
+	case l < 1<<16:
+		dst = append(dst, 0xda)
+		dst = binary.BigEndian.AppendUint16(dst, uint16(l))
+	default:
+		dst = append(dst, 0xdb)
+		dst = binary.BigEndian.AppendUint32(dst, uint32(l))
+	}
+	return append(dst, val...)
+}
+
+// UnmarshalMsgpack interns the msgpack string in b, which must hold nothing else, and
+// stores it in s. It implements the Unmarshaler interface of
+// github.com/vmihailenco/msgpack, so String fields are interned as they are decoded
+// there without this package depending on it. A msgpack nil leaves s empty.
+func (s *String) UnmarshalMsgpack(b []byte) error {
+	if len(b) == 1 && b[0] == 0xc0 {
+		*s = ""
+		return nil
+	}
+	raw, rest, err := msgpackString(b)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("%w: %d bytes after the string", ErrMsgpack, len(rest))
+	}
+	*s = String(DeduplicateBytes(raw))
+	return nil
+}
+
+// MarshalMsgpack encodes s as a msgpack string. It implements the Marshaler interface
+// of github.com/vmihailenco/msgpack.
+func (s String) MarshalMsgpack() ([]byte, error) {
+	return AppendMsgpackString(nil, string(s)), nil
+}
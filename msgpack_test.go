@@ -0,0 +1,72 @@
+package intern_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMsgpackString(t *testing.T) {
+	in := intern.New(16)
+	vals := []string{"", "hat", strings.Repeat("a", 31), strings.Repeat("b", 32), strings.Repeat("c", 300), strings.Repeat("d", 70000)}
+	var b []byte
+	for _, val := range vals {
+		b = intern.AppendMsgpackString(b, val)
+	}
+	assert.Equal(t, []byte{0xa0, 0xa3, 'h', 'a', 't', 0xbf}, b[:6])
+
+	rest := b
+	for _, want := range vals {
+		var val string
+		var err error
+		val, rest, err = in.ReadMsgpackString(rest)
+		assert.NoError(t, err)
+		assert.Equal(t, want, val)
+	}
+	assert.Empty(t, rest)
+	assert.Equal(t, len(vals), in.Len())
+
+	val, _, err := in.ReadMsgpackString([]byte{0xa3, 'h', 'a', 't'})
+	assert.NoError(t, err)
+	assert.Equal(t, unsafe.StringData(in.Deduplicate("hat")), unsafe.StringData(val))
+}
+
+func TestMsgpackStringErrors(t *testing.T) {
+	in := intern.New(16)
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{name: "empty", data: nil, err: "intern: invalid msgpack string: data is truncated"},
+		{name: "short", data: []byte{0xa3, 'h'}, err: "intern: invalid msgpack string: data is truncated"},
+		{name: "short header", data: []byte{0xda, 0}, err: "intern: invalid msgpack string: data is truncated"},
+		{name: "int", data: []byte{0x07}, err: "intern: invalid msgpack string: found type 0x7"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, rest, err := in.ReadMsgpackString(test.data)
+			assert.EqualError(t, err, test.err)
+			assert.True(t, errors.Is(err, intern.ErrMsgpack))
+			assert.Equal(t, test.data, rest)
+		})
+	}
+}
+
+func TestStringMsgpack(t *testing.T) {
+	b, err := intern.String("hat").MarshalMsgpack()
+	assert.NoError(t, err)
+
+	var s intern.String
+	assert.NoError(t, s.UnmarshalMsgpack(b))
+	assert.Equal(t, intern.String("hat"), s)
+	assert.Equal(t, unsafe.StringData(intern.Deduplicate("hat")), unsafe.StringData(string(s)))
+
+	assert.NoError(t, s.UnmarshalMsgpack([]byte{0xc0}))
+	assert.Equal(t, intern.String(""), s)
+	assert.EqualError(t, s.UnmarshalMsgpack(append(b, 0)), "intern: invalid msgpack string: 1 bytes after the string")
+}
@@ -0,0 +1,145 @@
+package intern
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// Option configures an Intern created with New
+type Option func(i *Intern)
+
+// ErrMaxBytes is returned when a new string can't be stored without exceeding the memory
+// budget set with WithMaxBytes
+var ErrMaxBytes = errors.New("intern: memory budget exceeded")
+
+// ErrTooLarge is returned when a new string can't be stored because its offset would be
+// too large for an int. That can only happen where int is 32 bits, once about 2GB of
+// strings are stored. WriteSnapshot also returns it for more strings than its lookup
+// table can index.
+var ErrTooLarge = errors.New("intern: too many strings to address")
+
+// ErrFull is returned when a new string can't be stored because the limit set by
+// WithMaxEntries has been reached
+var ErrFull = errors.New("intern: maximum number of entries reached")
+
+// WithMaxBytes limits the memory used by the hash table and string arena to roughly n
+// bytes. Once the budget is reached new strings are no longer stored: TrySave returns
+// ErrMaxBytes and Deduplicate returns its argument un-interned. Strings already stored
+// can still be found.
+func WithMaxBytes(n int) Option {
+	return func(i *Intern) {
+		i.maxBytes = n
+	}
+}
+
+// WithMaxEntries limits the number of unique strings stored to n, and stops the table
+// growing any larger than it needs to be to hold them. This protects memory-constrained
+// processes from data with runaway cardinality. Once the limit is reached new strings are
+// no longer stored: TrySave returns ErrFull and Deduplicate returns its argument
+// un-interned. Strings already stored can still be found.
+func WithMaxEntries(n int) Option {
+	return func(i *Intern) {
+		i.entryLimit = n
+	}
+}
+
+// WithProbeStats records the probe length of one in every sampleEvery table lookups, so
+// that Stats can report a histogram of them. Pass 1 to record every lookup.
+func WithProbeStats(sampleEvery int) Option {
+	return func(i *Intern) {
+		i.probes.sampleEvery = sampleEvery
+	}
+}
+
+// WithDeterministic makes the layout of the table depend only on the strings saved and
+// the order they were saved in. It replaces the runtime's hash function, which is seeded
+// randomly in each process, with one that always gives the same result for a given seed.
+// Offsets, iteration order, and the points at which the table grows are then identical
+// from run to run, which is useful for golden-file tests and reproducible dictionaries.
+// The deterministic hash is slower than the default one.
+func WithDeterministic(seed uint64) Option {
+	return func(i *Intern) {
+		i.hasher = fnvHash
+		i.seed = seed
+	}
+}
+
+// WithHasher replaces the default hash function with h. Strings shorter than 8 bytes are
+// not hashed at all, so h is only used for longer ones.
+func WithHasher(h Hasher) Option {
+	return func(i *Intern) {
+		i.hasher = h
+	}
+}
+
+// WithBloomFilter keeps a Bloom filter of the strings stored, so that most lookups for
+// strings that aren't present return without probing the table. This suits workloads such
+// as checking membership against a mostly static dictionary where most lookups miss. The
+// filter costs between one and three bytes per entry.
+func WithBloomFilter() Option {
+	return func(i *Intern) {
+		i.filter = &bloomFilter{}
+	}
+}
+
+// WithHugePages asks the kernel to back the hash table and string storage with
+// transparent huge pages, which cuts the TLB misses that make up a noticeable part of
+// lookup cost in tables with many millions of entries. Strings are stored in 2MB chunks
+// rather than 256KB ones unless WithChunkSize says otherwise, and tables of at least 2MB
+// are aligned to huge pages, which can waste up to 2MB each. It is only advice: it has no
+// effect if the kernel has transparent huge pages disabled. Outside Linux the strings are
+// still stored in 2MB chunks, but nothing is aligned.
+func WithHugePages() Option {
+	return func(i *Intern) {
+		i.hugePages = true
+		i.arena.huge = true
+	}
+}
+
+// WithChunkSize sets the size of the chunks of memory that strings are copied into. It is
+// 256KB by default, or 2MB with WithHugePages. Small chunks keep the memory held by an
+// Intern of a few short strings down, while large ones suit long strings such as log
+// messages, needing fewer allocations. The size is rounded up to a power of 2 between
+// 256 bytes and 1GB. A string longer than a chunk is given several.
+func WithChunkSize(n int) Option {
+	return func(i *Intern) {
+		n = min(max(n, 1<<arenaMinChunkShift), 1<<arenaMaxChunkShift)
+		i.arena.shift = uint(bits.Len(uint(n - 1)))
+	}
+}
+
+// WithAdaptiveGrowth lets the load factor at which the table grows vary between 1/2 and
+// 7/8, rather than staying at 3/4. The mean number of slots examined by recent inserts is
+// compared against targetProbes: the table grows sooner when probe sequences are longer
+// than that, and is allowed to fill further when they are well under half of it. This
+// keeps latency steady across key sets that spread very differently through the table.
+// It has no effect with WithCuckoo, where lookups never examine more than two buckets.
+func WithAdaptiveGrowth(targetProbes float64) Option {
+	return func(i *Intern) {
+		i.growth.target = targetProbes
+	}
+}
+
+// WithBackgroundResize moves the work of copying entries to a new, larger table out of
+// the write path. Normally each write during a resize copies a few entries; with this
+// option a Shared interner does the copying in a background goroutine instead, so writes
+// don't pay for it. If the writes outpace the goroutine and the new table fills up
+// before the copy is finished, the writer that notices finishes it. A plain Intern has no
+// background goroutine, so with this option it copies everything in one go at that point.
+func WithBackgroundResize() Option {
+	return func(i *Intern) {
+		i.deferMigration = true
+	}
+}
+
+// WithCuckoo uses cuckoo hashing in place of linear probing. A lookup then examines at
+// most two buckets of four slots, however full the table is and however unlucky the
+// hashes, which suits latency-critical lookups where the tail matters. The price is paid
+// on insert: a new string may have to move others around, and the table grows by
+// rebuilding itself in one go rather than incrementally, so occasional inserts are slow.
+// With WithProbeStats, lookups record the number of buckets examined.
+func WithCuckoo() Option {
+	return func(i *Intern) {
+		i.cuckoo = &cuckooTable{}
+	}
+}
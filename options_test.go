@@ -0,0 +1,130 @@
+package intern_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaxBytes(t *testing.T) {
+	in := intern.New(16, intern.WithMaxBytes(300*1024))
+
+	var stored int
+	for j := 0; j < 10000; j++ {
+		val := strconv.Itoa(j)
+		_, err := in.TrySave(val)
+		if err != nil {
+			assert.Equal(t, intern.ErrMaxBytes, err)
+			break
+		}
+		stored++
+	}
+	assert.True(t, stored > 0 && stored < 10000, stored)
+	assert.Equal(t, stored, in.Len())
+
+	// Strings we already have can still be found
+	offset, err := in.TrySave("0")
+	assert.NoError(t, err)
+	assert.Equal(t, "0", in.Get(offset))
+
+	// New strings are passed through
+	val := strconv.Itoa(20000)
+	assert.Equal(t, val, in.Deduplicate(val))
+	assert.Equal(t, datapointer(val), datapointer(in.Deduplicate(val)))
+	assert.Equal(t, stored, in.Len())
+	assert.Panics(t, func() { in.Save(val) })
+
+	vals := []string{"1", val}
+	in.DeduplicateInPlace(vals)
+	assert.Equal(t, []string{"1", val}, vals)
+	assert.Equal(t, stored, in.Len())
+}
+
+func TestDeterministic(t *testing.T) {
+	build := func() *intern.Intern {
+		in := intern.New(16, intern.WithDeterministic(42))
+		for _, val := range []string{"red", "green", "blue", "cyan", "magenta", "yellow", "black", "white", "aquamarine", "chartreuse"} {
+			in.Save(val)
+		}
+		return in
+	}
+
+	var order []string
+	for _, val := range build().All() {
+		order = append(order, val)
+	}
+	// The table layout must not change from run to run
+	assert.Equal(t, []string{"white", "magenta", "black", "yellow", "cyan", "blue", "chartreuse", "red", "green", "aquamarine"}, order)
+
+	a, b := build(), build()
+	for j := 0; j < 1000; j++ {
+		val := strconv.Itoa(j)
+		assert.Equal(t, a.Save(val), b.Save(val))
+	}
+	var dumpA, dumpB strings.Builder
+	assert.NoError(t, a.Dump(&dumpA))
+	assert.NoError(t, b.Dump(&dumpB))
+	assert.Equal(t, dumpA.String(), dumpB.String())
+}
+
+func TestMaxEntries(t *testing.T) {
+	in := intern.New(16, intern.WithMaxEntries(1000))
+	for j := 0; j < 1000; j++ {
+		_, err := in.TrySave(strconv.Itoa(j))
+		assert.NoError(t, err)
+	}
+	offset, err := in.TrySave("1000")
+	assert.Equal(t, intern.ErrFull, err)
+	assert.Equal(t, intern.InvalidOffset, offset)
+	assert.Panics(t, func() { in.Save("1000") })
+	val := strconv.Itoa(2000)
+	assert.Equal(t, datapointer(val), datapointer(in.Deduplicate(val)))
+
+	// Existing strings are still found, and the table grew no further than it had to
+	offset, err = in.TrySave("999")
+	assert.NoError(t, err)
+	assert.Equal(t, "999", in.Get(offset))
+	assert.Equal(t, 1000, in.Len())
+	assert.Equal(t, 2048, in.Cap())
+
+	in.Reserve(1000)
+	assert.Equal(t, 2048, in.Cap())
+}
+
+func TestChunkSize(t *testing.T) {
+	tests := []struct {
+		size  int
+		chunk int
+	}{
+		{size: 4096, chunk: 4096},
+		{size: 5000, chunk: 8192},
+		{size: 1, chunk: 256},
+		{size: 8 << 20, chunk: 8 << 20},
+	}
+	for _, test := range tests {
+		for _, opts := range [][]intern.Option{
+			{intern.WithChunkSize(test.size)},
+			{intern.WithChunkSize(test.size), intern.WithHugePages()},
+		} {
+			in := intern.New(16, opts...)
+			in.Save("hat")
+			assert.Equal(t, test.chunk, in.MemoryUsage().Strings)
+
+			vals := make([]string, 1000)
+			for j := range vals {
+				vals[j] = strings.Repeat("x", j%300) + strconv.Itoa(j)
+				in.Save(vals[j])
+			}
+			for _, val := range vals {
+				offset, ok := in.Lookup(val)
+				assert.True(t, ok)
+				assert.Equal(t, val, in.Get(offset))
+			}
+			assert.Zero(t, in.MemoryUsage().Strings%test.chunk)
+			assert.NoError(t, in.CheckInvariants())
+		}
+	}
+}
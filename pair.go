@@ -0,0 +1,48 @@
+package intern
+
+// DeduplicatePair is Deduplicate for a key and its value, such as a metric label. The
+// strings are stored just as two calls to Deduplicate would store them, including
+// doing a share of any resize in progress for each; Shared.DeduplicatePair takes its
+// lock once for both. If either string is new and can't be stored then neither is
+// stored, and any string not already present is returned as it is.
+func (i *Intern) DeduplicatePair(k, v string) (string, string) {
+	ko, vo, kerr, verr := i.savePair(k, v)
+	if kerr == nil {
+		k = i.Get(ko)
+	}
+	if verr == nil {
+		v = i.Get(vo)
+	}
+	return k, v
+}
+
+// SavePair is Save for a key and its value. Like Save, it panics if either string is
+// new and can't be stored, in which case neither is stored.
+func (i *Intern) SavePair(k, v string) (ko, vo int) {
+	ko, vo, kerr, verr := i.savePair(k, v)
+	if kerr != nil {
+		panic(kerr)
+	}
+	if verr != nil {
+		panic(verr)
+	}
+	return ko, vo
+}
+
+// savePair stores k and v, reporting the outcome for each. If both are new, room is
+// checked for the two together, so that either both are stored or neither is.
+func (i *Intern) savePair(k, v string) (ko, vo int, kerr, verr error) {
+	i.resize()
+	if k != v {
+		_, kok := i.find(k)
+		_, vok := i.find(v)
+		if !kok && !vok {
+			if err := i.checkBudgetPair(k, v); err != nil {
+				return InvalidOffset, InvalidOffset, err, err
+			}
+		}
+	}
+	ko, kerr = i.save(k)
+	vo, verr = i.TrySave(v)
+	return ko, vo, kerr, verr
+}
@@ -0,0 +1,95 @@
+package intern_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeduplicatePair(t *testing.T) {
+	for name, opts := range map[string][]intern.Option{
+		"linear": nil,
+		"cuckoo": {intern.WithCuckoo()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			in := intern.New(16, opts...)
+			// Keys repeat while values are new, so the table fills by one or two strings
+			// at a time
+			for j := 0; j < 1000; j++ {
+				k, v := in.DeduplicatePair("key"+strconv.Itoa(j%7), strconv.Itoa(j))
+				assert.Equal(t, "key"+strconv.Itoa(j%7), k)
+				assert.Equal(t, strconv.Itoa(j), v)
+			}
+			assert.Equal(t, 1007, in.Len())
+			assert.NoError(t, in.CheckInvariants())
+
+			ko, vo := in.SavePair("key1", "10")
+			assert.Equal(t, in.Save("key1"), ko)
+			assert.Equal(t, in.Save("10"), vo)
+		})
+	}
+}
+
+func TestDeduplicatePairFull(t *testing.T) {
+	// There is room for one more string, but not for both
+	in := intern.New(16, intern.WithMaxEntries(1))
+	k, v := in.DeduplicatePair("key", "value")
+	assert.Equal(t, "key", k)
+	assert.Equal(t, "value", v)
+	assert.Equal(t, 0, in.Len())
+	assert.Panics(t, func() { in.SavePair("key", "value") })
+	assert.Equal(t, 0, in.Len())
+
+	// A pair with one new string only needs room for that one
+	key := in.Save("key")
+	ko, vo := in.SavePair("key", "key")
+	assert.Equal(t, key, ko)
+	assert.Equal(t, key, vo)
+	val := "value"
+	k, v = in.DeduplicatePair("key", val)
+	assert.Equal(t, datapointer(in.Get(key)), datapointer(k))
+	assert.Equal(t, datapointer(val), datapointer(v))
+}
+
+func TestDeduplicatePairMaxBytes(t *testing.T) {
+	// The first string takes most of a chunk. The key of the pair fits in what's left,
+	// but the value needs a new chunk, which the budget doesn't allow
+	first := strings.Repeat("a", 200_000)
+	k, v := strings.Repeat("k", 40_000), strings.Repeat("v", 40_000)
+	probe := intern.New(16)
+	probe.Save(first)
+	in := intern.New(16, intern.WithMaxBytes(probe.MemoryUsage().Total()+1000))
+	in.Save(first)
+
+	gotK, gotV := in.DeduplicatePair(k, v)
+	assert.Equal(t, datapointer(k), datapointer(gotK))
+	assert.Equal(t, datapointer(v), datapointer(gotV))
+	assert.Equal(t, 1, in.Len())
+	_, _, err := func() (ko, vo int, err error) {
+		defer func() { err, _ = recover().(error) }()
+		ko, vo = in.SavePair(k, v)
+		return ko, vo, nil
+	}()
+	assert.Equal(t, intern.ErrMaxBytes, err)
+	assert.Equal(t, 1, in.Len())
+
+	// Either string alone still fits
+	_, err = in.TrySave(k)
+	assert.NoError(t, err)
+}
+
+func TestDeduplicatePairIncremental(t *testing.T) {
+	in := intern.New(16)
+	// A resize in progress is left for later writes to carry on with, rather than being
+	// finished in one go
+	var resizing bool
+	for j := 0; j < 1000 && !resizing; j++ {
+		in.DeduplicatePair("key", strconv.Itoa(j))
+		resizing = in.MemoryUsage().OldTable != 0
+	}
+	assert.True(t, resizing)
+	assert.NoError(t, in.CheckInvariants())
+}
@@ -0,0 +1,53 @@
+package intern
+
+import (
+	"context"
+	"math"
+	"runtime/metrics"
+	"time"
+)
+
+// Metrics read by WatchMemory. The memory limit applies to all the memory the runtime
+// has mapped, less what it has returned to the OS
+const (
+	metricMemoryLimit = "/gc/gomemlimit:bytes"
+	metricTotal       = "/memory/classes/total:bytes"
+	metricReleased    = "/memory/classes/heap/released:bytes"
+)
+
+// WatchMemory checks the process's memory use every interval, and calls fn whenever it
+// is at least threshold times the limit set with debug.SetMemoryLimit or GOMEMLIMIT. fn
+// is passed the memory in use and the limit, both in bytes, as the runtime counts them.
+// It might react by calling Evict or Collect on an interner, which gives the space back
+// the next time the garbage collector runs. fn is called again at the next check if
+// memory use is still high. Nothing happens if no memory limit is set.
+//
+// WatchMemory returns once ctx is done, so it is usually run in its own goroutine. Use a
+// Shared interner if fn is to change one that other goroutines use.
+func WatchMemory(ctx context.Context, interval time.Duration, threshold float64, fn func(used, limit uint64)) {
+	samples := []metrics.Sample{
+		{Name: metricMemoryLimit},
+		{Name: metricTotal},
+		{Name: metricReleased},
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		metrics.Read(samples)
+		limit := samples[0].Value.Uint64()
+		used := samples[1].Value.Uint64() - samples[2].Value.Uint64()
+		if limit == math.MaxInt64 {
+			// There is no limit
+			continue
+		}
+		if float64(used) >= threshold*float64(limit) {
+			fn(used, limit)
+		}
+	}
+}
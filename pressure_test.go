@@ -0,0 +1,54 @@
+package intern_test
+
+import (
+	"context"
+	"runtime/debug"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchMemory(t *testing.T) {
+	s := intern.NewShared(16)
+	for j := range 1000 {
+		s.Save("value-" + strconv.Itoa(j))
+	}
+
+	// A limit far above what the test uses, and a threshold low enough to pass anyway
+	old := debug.SetMemoryLimit(1 << 40)
+	defer debug.SetMemoryLimit(old)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		intern.WatchMemory(ctx, time.Millisecond, 0, func(used, limit uint64) {
+			assert.Equal(t, uint64(1<<40), limit)
+			assert.True(t, used > 0)
+			s.Evict(s.Len() / 2)
+			cancel()
+		})
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("fn was not called")
+	}
+	assert.Equal(t, 500, s.Len())
+}
+
+func TestWatchMemoryUnderLimit(t *testing.T) {
+	old := debug.SetMemoryLimit(1 << 40)
+	defer debug.SetMemoryLimit(old)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	intern.WatchMemory(ctx, time.Millisecond, 0.9, func(used, limit uint64) {
+		t.Errorf("called with %d of %d bytes used", used, limit)
+	})
+}
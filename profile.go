@@ -0,0 +1,140 @@
+package intern
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// insertProfileDepth is the number of stack frames recorded for each sampled insert
+const insertProfileDepth = 32
+
+// insertProfile records where new strings come from. It keeps the call stack of one in
+// every so many inserts, along with the bytes each sampled string takes up.
+type insertProfile struct {
+	every     int
+	countdown int
+	sites     map[[insertProfileDepth]uintptr]*insertSite
+}
+
+// insertSite totals the sampled inserts made from one call stack
+type insertSite struct {
+	strings int
+	bytes   int
+}
+
+// WithInsertProfile records the call stack of one in every sampleEvery new strings, so
+// that WriteHeapBreakdown can attribute the memory used by the strings to the code that
+// saved them. This helps track down the code path that is filling the dictionary with
+// strings that are never seen again. Pass 1 to record every insert. Each sample costs a
+// stack walk, so large values suit production use.
+func WithInsertProfile(sampleEvery int) Option {
+	return func(i *Intern) {
+		i.profile = &insertProfile{
+			every:     max(sampleEvery, 1),
+			countdown: max(sampleEvery, 1),
+			sites:     make(map[[insertProfileDepth]uintptr]*insertSite),
+		}
+	}
+}
+
+// record notes an insert of a string taking up size bytes
+func (p *insertProfile) record(size int) {
+	p.countdown--
+	if p.countdown > 0 {
+		return
+	}
+	p.countdown = p.every
+
+	var stack [insertProfileDepth]uintptr
+	// Skip runtime.Callers and record itself. Frames within this package are trimmed
+	// when the profile is written, as their number depends on how the string was saved
+	runtime.Callers(2, stack[:])
+	site, ok := p.sites[stack]
+	if !ok {
+		site = &insertSite{}
+		p.sites[stack] = site
+	}
+	site.strings++
+	site.bytes += size
+}
+
+// packagePrefix is the prefix of the names of functions in this package
+var packagePrefix = reflect.TypeOf(Intern{}).PkgPath() + "."
+
+// WriteHeapBreakdown writes a report to w attributing the memory used by strings to the
+// call sites that saved them, largest first. Figures are estimated by scaling up the
+// inserts sampled by WithInsertProfile, and cover every string saved, including any
+// since dropped by Collect or Evict. Nothing is written if the Intern was created
+// without WithInsertProfile.
+func (i *Intern) WriteHeapBreakdown(w io.Writer) error {
+	p := i.profile
+	if p == nil {
+		return nil
+	}
+
+	type site struct {
+		frames []runtime.Frame
+		insertSite
+	}
+	// Stacks that differ only within this package belong to the same call site
+	merged := make(map[string]*site, len(p.sites))
+	var key strings.Builder
+	for stack, s := range p.sites {
+		frames := callerFrames(stack[:])
+		key.Reset()
+		for _, f := range frames {
+			fmt.Fprintf(&key, "%s:%d\n", f.Function, f.Line)
+		}
+		m, ok := merged[key.String()]
+		if !ok {
+			m = &site{frames: frames}
+			merged[key.String()] = m
+		}
+		m.strings += s.strings
+		m.bytes += s.bytes
+	}
+	sites := make([]*site, 0, len(merged))
+	var total insertSite
+	for _, s := range merged {
+		sites = append(sites, s)
+		total.strings += s.strings
+		total.bytes += s.bytes
+	}
+	slices.SortFunc(sites, func(a, b *site) int {
+		return b.bytes - a.bytes
+	})
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "sampled 1 in every %d inserts: about %d bytes in %d strings\n", p.every, total.bytes*p.every, total.strings*p.every)
+	for _, s := range sites {
+		fmt.Fprintf(b, "\n%d bytes in %d strings (%.1f%%)\n", s.bytes*p.every, s.strings*p.every, 100*float64(s.bytes)/float64(total.bytes))
+		for _, f := range s.frames {
+			fmt.Fprintf(b, "  %s\n      %s:%d\n", f.Function, f.File, f.Line)
+		}
+	}
+	return b.Flush()
+}
+
+// callerFrames returns the frames of stack, leaving out those within this package at
+// its start
+func callerFrames(stack []uintptr) []runtime.Frame {
+	if n := slices.Index(stack, 0); n >= 0 {
+		stack = stack[:n]
+	}
+	var frames []runtime.Frame
+	it := runtime.CallersFrames(stack)
+	for {
+		f, more := it.Next()
+		if len(frames) != 0 || !strings.HasPrefix(f.Function, packagePrefix) {
+			frames = append(frames, f)
+		}
+		if !more {
+			return frames
+		}
+	}
+}
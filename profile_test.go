@@ -0,0 +1,52 @@
+package intern_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+//go:noinline
+func saveJunk(in *intern.Intern) {
+	for j := range 900 {
+		in.Save("junk-" + strconv.Itoa(j) + strings.Repeat(".", 20))
+	}
+}
+
+//go:noinline
+func saveWords(in *intern.Intern) {
+	for j := range 100 {
+		in.Deduplicate("w" + strconv.Itoa(j))
+	}
+}
+
+func TestWriteHeapBreakdown(t *testing.T) {
+	in := intern.New(16, intern.WithInsertProfile(10))
+	saveJunk(in)
+	saveWords(in)
+
+	var b strings.Builder
+	assert.NoError(t, in.WriteHeapBreakdown(&b))
+	report := b.String()
+	assert.True(t, strings.HasPrefix(report, "sampled 1 in every 10 inserts: about "), report)
+	assert.Contains(t, report, "in 1000 strings\n")
+	assert.Contains(t, report, "in 900 strings (")
+	assert.Contains(t, report, "in 100 strings (")
+
+	// The heaviest call site comes first, and frames within the package are left out
+	junk := strings.Index(report, "intern_test.saveJunk")
+	words := strings.Index(report, "intern_test.saveWords")
+	assert.True(t, junk > 0 && words > junk, report)
+	assert.NotContains(t, report, "intern.(*Intern)")
+}
+
+func TestWriteHeapBreakdownDisabled(t *testing.T) {
+	in := intern.New(16)
+	in.Save("hat")
+	var b strings.Builder
+	assert.NoError(t, in.WriteHeapBreakdown(&b))
+	assert.Empty(t, b.String())
+}
@@ -0,0 +1,23 @@
+package intern
+
+import (
+	"bufio"
+	"io"
+)
+
+// SaveTokens splits the contents of r into tokens using split, as a bufio.Scanner would,
+// and saves every token. It returns the number of times each token was seen, keyed by the
+// token's offset. Pass bufio.ScanWords to build a vocabulary from a text file.
+func (i *Intern) SaveTokens(r io.Reader, split bufio.SplitFunc) (map[int]int, error) {
+	counts := make(map[int]int)
+	scanner := bufio.NewScanner(r)
+	scanner.Split(split)
+	for scanner.Scan() {
+		offset, err := i.saveBytes(scanner.Bytes())
+		if err != nil {
+			return counts, err
+		}
+		counts[offset]++
+	}
+	return counts, scanner.Err()
+}
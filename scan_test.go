@@ -0,0 +1,41 @@
+package intern_test
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveTokens(t *testing.T) {
+	in := intern.New(16)
+
+	counts, err := in.SaveTokens(strings.NewReader("the cat sat on\nthe mat  the end"), bufio.ScanWords)
+	assert.NoError(t, err)
+	assert.Equal(t, 6, in.Len())
+
+	words := make(map[string]int)
+	for offset, count := range counts {
+		words[in.Get(offset)] = count
+	}
+	assert.Equal(t, map[string]int{
+		"the": 3,
+		"cat": 1,
+		"sat": 1,
+		"on":  1,
+		"mat": 1,
+		"end": 1,
+	}, words)
+}
+
+func TestSaveTokensError(t *testing.T) {
+	in := intern.New(16)
+
+	oops := errors.New("oops")
+	_, err := in.SaveTokens(iotest.ErrReader(oops), bufio.ScanLines)
+	assert.Equal(t, oops, err)
+}
@@ -0,0 +1,119 @@
+package intern
+
+import "time"
+
+// Segmented is an interner that forgets strings by age. Time is divided into windows,
+// and each window has its own segment of storage. Strings are saved in the segment for
+// the current window, and once a segment is older than the retention period it is dropped
+// as a whole. Expiry therefore costs nothing per string: there are no timestamps to keep
+// and nothing to scan.
+//
+// A string seen in several windows is stored once in each of their segments, so it lives
+// for as long as it keeps being seen. As with Generational there are no stable offsets,
+// and strings returned from a dropped segment stay valid until the garbage collector finds
+// nothing refers to them.
+type Segmented struct {
+	cap    int
+	window time.Duration
+	retain int
+	// segments holds the live segments, oldest first
+	segments []segment
+	// onEvict holds the callbacks registered with OnEvict, which every segment shares
+	onEvict []func(offset int, s string)
+}
+
+// segment is the storage for one window
+type segment struct {
+	start time.Time
+	in    *Intern
+}
+
+// NewSegmented creates a Segmented interner that keeps the segments for the most recent
+// retain windows of the given length. Each segment starts with a table of size cap.
+func NewSegmented(cap int, window time.Duration, retain int) *Segmented {
+	return &Segmented{
+		cap:      cap,
+		window:   window,
+		retain:   max(retain, 1),
+		segments: []segment{{in: New(cap)}},
+	}
+}
+
+// Advance moves the interner on to the window holding now, starting a new segment if now
+// is in a later window than the current one, and dropping segments that have passed out
+// of the retention period. Segmented doesn't read the clock itself, so call Advance
+// regularly with the current time, or with the timestamps of the events being
+// processed. Times earlier than the current window are ignored.
+func (s *Segmented) Advance(now time.Time) {
+	start := now.Truncate(s.window)
+	if !start.After(s.segments[len(s.segments)-1].start) {
+		return
+	}
+	in := New(s.cap)
+	in.onEvict = s.onEvict
+	s.segments = append(s.segments, segment{start: start, in: in})
+
+	// Keep the segments for windows that start after this cutoff
+	cutoff := start.Add(-time.Duration(s.retain) * s.window)
+	var drop int
+	for drop < len(s.segments)-1 && !s.segments[drop].start.After(cutoff) {
+		drop++
+	}
+	dropped := s.segments[:drop]
+	s.segments = s.segments[drop:]
+	if len(s.onEvict) != 0 {
+		for _, seg := range dropped {
+			for offset, val := range seg.in.All() {
+				if _, ok := s.Lookup(val); !ok {
+					seg.in.evicted(offset)
+				}
+			}
+		}
+	}
+	clear(dropped)
+}
+
+// OnEvict registers fn to be called for each string that Advance drops because no live
+// segment holds it any longer, as Intern.OnEvict does for an Intern. The offset is the
+// string's offset in the segment being dropped. A string held in several of the dropped
+// segments is reported once for each. Callbacks run synchronously within Advance, in the
+// order they were registered.
+func (s *Segmented) OnEvict(fn func(offset int, s string)) {
+	s.onEvict = append(s.onEvict, fn)
+	for _, seg := range s.segments {
+		seg.in.onEvict = s.onEvict
+	}
+}
+
+// Deduplicate returns a stored version of val. Within a window this is always backed by
+// the same memory for the same string.
+func (s *Segmented) Deduplicate(val string) string {
+	return s.segments[len(s.segments)-1].in.Deduplicate(val)
+}
+
+// Lookup returns the stored version of val and true if it is held in any live segment,
+// without storing it
+func (s *Segmented) Lookup(val string) (string, bool) {
+	for j := len(s.segments) - 1; j >= 0; j-- {
+		in := s.segments[j].in
+		if offset, ok := in.find(val); ok {
+			return in.Get(offset), true
+		}
+	}
+	return "", false
+}
+
+// Len returns the number of strings stored across the live segments. A string stored in
+// more than one segment is counted once for each.
+func (s *Segmented) Len() int {
+	var n int
+	for _, seg := range s.segments {
+		n += seg.in.Len()
+	}
+	return n
+}
+
+// Segments returns the number of live segments
+func (s *Segmented) Segments() int {
+	return len(s.segments)
+}
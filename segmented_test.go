@@ -0,0 +1,95 @@
+package intern_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSegmented(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := intern.NewSegmented(16, time.Minute, 3)
+	s.Advance(start)
+
+	hat := s.Deduplicate("hat")
+	assert.Equal(t, "hat", hat)
+	assert.Equal(t, datapointer(hat), datapointer(s.Deduplicate("hat")))
+	s.Deduplicate("scarf")
+	assert.Equal(t, 2, s.Len())
+
+	// Later in the same window nothing changes
+	s.Advance(start.Add(59 * time.Second))
+	assert.Equal(t, 1, s.Segments())
+
+	// hat is seen again in the next window, so is stored in its segment too
+	s.Advance(start.Add(time.Minute))
+	assert.Equal(t, 2, s.Segments())
+	hat2 := s.Deduplicate("hat")
+	assert.NotEqual(t, datapointer(hat), datapointer(hat2))
+	assert.Equal(t, 3, s.Len())
+
+	val, ok := s.Lookup("hat")
+	assert.True(t, ok)
+	assert.Equal(t, datapointer(hat2), datapointer(val))
+	val, ok = s.Lookup("scarf")
+	assert.True(t, ok)
+	assert.Equal(t, "scarf", val)
+
+	// Going back in time is ignored
+	s.Advance(start)
+	assert.Equal(t, 2, s.Segments())
+
+	// After three windows the first segment has gone, taking scarf with it. No
+	// segment was started for the window in between
+	s.Advance(start.Add(3 * time.Minute))
+	assert.Equal(t, 2, s.Segments())
+	_, ok = s.Lookup("scarf")
+	assert.False(t, ok)
+	_, ok = s.Lookup("hat")
+	assert.True(t, ok)
+
+	// A long gap drops everything but the new segment
+	s.Advance(start.Add(time.Hour))
+	assert.Equal(t, 1, s.Segments())
+	assert.Equal(t, 0, s.Len())
+	_, ok = s.Lookup("hat")
+	assert.False(t, ok)
+
+	// Strings from dropped segments are still valid
+	assert.Equal(t, "hat", hat)
+	assert.Equal(t, "hat", hat2)
+}
+
+func TestSegmentedNoAdvance(t *testing.T) {
+	s := intern.NewSegmented(16, time.Minute, 0)
+	assert.Equal(t, "hat", s.Deduplicate("hat"))
+	_, ok := s.Lookup("hat")
+	assert.True(t, ok)
+	assert.Equal(t, 1, s.Segments())
+}
+
+func TestSegmentedOnEvict(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	s := intern.NewSegmented(16, time.Minute, 2)
+	s.Advance(start)
+
+	var evicted []string
+	s.OnEvict(func(offset int, val string) {
+		evicted = append(evicted, val)
+	})
+
+	s.Deduplicate("hat")
+	s.Deduplicate("scarf")
+	s.Advance(start.Add(time.Minute))
+	s.Deduplicate("hat")
+	assert.Empty(t, evicted)
+
+	// The first segment is dropped, but hat is still held in the second
+	s.Advance(start.Add(2 * time.Minute))
+	assert.Equal(t, []string{"scarf"}, evicted)
+
+	s.Advance(start.Add(3 * time.Minute))
+	assert.Equal(t, []string{"scarf", "hat"}, evicted)
+}
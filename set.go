@@ -0,0 +1,85 @@
+package intern
+
+import (
+	"iter"
+	"maps"
+	"math/bits"
+	"slices"
+)
+
+// Set is a set of offsets of strings stored in an Intern, which makes a cheap set of
+// strings. It is a sparse bitset: offsets are spread out by the lengths of the strings, so
+// bits are kept 64 at a time for only the parts of the offset range in use. The zero value
+// is an empty set ready to use. Only combine sets of offsets from the same Intern.
+type Set struct {
+	words map[int]uint64
+}
+
+// Add adds offset to the set
+func (s *Set) Add(offset int) {
+	if s.words == nil {
+		s.words = make(map[int]uint64)
+	}
+	s.words[offset>>6] |= 1 << (offset & 63)
+}
+
+// Remove removes offset from the set
+func (s *Set) Remove(offset int) {
+	w, ok := s.words[offset>>6]
+	if !ok {
+		return
+	}
+	if w &^= 1 << (offset & 63); w == 0 {
+		delete(s.words, offset>>6)
+	} else {
+		s.words[offset>>6] = w
+	}
+}
+
+// Has returns true if offset is in the set
+func (s *Set) Has(offset int) bool {
+	return s.words[offset>>6]&(1<<(offset&63)) != 0
+}
+
+// Len returns the number of offsets in the set
+func (s *Set) Len() int {
+	var n int
+	for _, w := range s.words {
+		n += bits.OnesCount64(w)
+	}
+	return n
+}
+
+// Union adds every offset in o to s
+func (s *Set) Union(o *Set) {
+	if len(o.words) != 0 && s.words == nil {
+		s.words = make(map[int]uint64, len(o.words))
+	}
+	for k, w := range o.words {
+		s.words[k] |= w
+	}
+}
+
+// Intersect removes every offset from s that is not also in o
+func (s *Set) Intersect(o *Set) {
+	for k, w := range s.words {
+		if w &= o.words[k]; w == 0 {
+			delete(s.words, k)
+		} else {
+			s.words[k] = w
+		}
+	}
+}
+
+// All returns an iterator over the offsets in the set in increasing order
+func (s *Set) All() iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for _, k := range slices.Sorted(maps.Keys(s.words)) {
+			for w := s.words[k]; w != 0; w &= w - 1 {
+				if !yield(k<<6 | bits.TrailingZeros64(w)) {
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package intern_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	var in intern.Intern
+	offsets := make(map[string]int)
+	for _, val := range []string{"red", "green", "blue", "cyan", "magenta", "yellow"} {
+		offsets[val] = in.Save(val)
+	}
+
+	var warm, rgb intern.Set
+	for _, val := range []string{"red", "magenta", "yellow"} {
+		warm.Add(offsets[val])
+	}
+	for _, val := range []string{"red", "green", "blue"} {
+		rgb.Add(offsets[val])
+	}
+	assert.Equal(t, 3, warm.Len())
+	assert.True(t, warm.Has(offsets["red"]))
+	assert.False(t, warm.Has(offsets["blue"]))
+
+	both := intern.Set{}
+	both.Union(&warm)
+	both.Intersect(&rgb)
+	assert.Equal(t, []int{offsets["red"]}, slices.Collect(both.All()))
+
+	warm.Union(&rgb)
+	assert.Equal(t, 5, warm.Len())
+	var vals []string
+	for offset := range warm.All() {
+		vals = append(vals, in.Get(offset))
+	}
+	// Offsets come out in the order the strings were saved
+	assert.Equal(t, []string{"red", "green", "blue", "magenta", "yellow"}, vals)
+
+	warm.Remove(offsets["red"])
+	warm.Remove(offsets["cyan"])
+	assert.False(t, warm.Has(offsets["red"]))
+	assert.Equal(t, 4, warm.Len())
+
+	var empty intern.Set
+	assert.False(t, empty.Has(0))
+	empty.Remove(0)
+	empty.Intersect(&rgb)
+	assert.Zero(t, empty.Len())
+}
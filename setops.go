@@ -0,0 +1,108 @@
+package intern
+
+import "slices"
+
+// Union returns a new Intern holding every string in a or b. The new Intern shares a's
+// stored strings rather than copying them, so a string keeps the offset it had in a.
+// Strings only in b are copied in after them. It has the same options as a, apart from
+// OnInsert and OnEvict callbacks and tuples, which are not carried over, and the limits
+// set by WithMaxBytes and WithMaxEntries, which are dropped so that the union can always
+// hold the strings of both.
+func Union(a, b *Intern) *Intern {
+	u := a.clone()
+	u.maxBytes, u.entryLimit = 0, 0
+	for _, val := range b.AllInOrder() {
+		u.Save(val)
+	}
+	return u
+}
+
+// Intersect returns a new Intern holding the strings that are in both a and b. Like
+// Union, it shares a's stored strings, so each string keeps the offset it had in a and
+// nothing is copied. The new Intern uses the same hash function as a, but otherwise has
+// default options.
+func Intersect(a, b *Intern) *Intern {
+	return a.ExportWhere(func(val string) bool {
+		_, ok := b.find(val)
+		return ok
+	})
+}
+
+// ExportWhere returns a new Intern holding the strings in i for which pred returns true.
+// Like Intersect, it shares i's stored strings, so each string keeps its offset. This
+// extracts a smaller dictionary, such as just the strings seen more than some number of
+// times, without copying strings or saving them again.
+func (i *Intern) ExportWhere(pred func(val string) bool) *Intern {
+	var keep []slot
+	i.eachSlot(func(s slot) {
+		if pred(i.Get(s.index - 1)) {
+			keep = append(keep, s)
+		}
+	})
+
+	r := &Intern{
+		arena:  i.sharedArena(),
+		seed:   i.seed,
+		hasher: i.hasher,
+		count:  len(keep),
+	}
+	l := 16
+	for l*r.loadLimit()/16 <= len(keep) {
+		l *= 2
+	}
+	r.table = newTable(l, false)
+	for _, s := range keep {
+		r.copyEntryToTable(r.table, s.index, s.hash)
+	}
+	return r
+}
+
+// clone returns a copy of i that shares its stored strings. Each can go on to store new
+// strings without affecting the other.
+func (i *Intern) clone() *Intern {
+	c := *i
+	c.arena = i.sharedArena()
+	c.table.slots = slices.Clone(i.table.slots)
+	c.oldTable.slots = slices.Clone(i.oldTable.slots)
+	if i.cuckoo != nil {
+		cuckoo := *i.cuckoo
+		cuckoo.slots = slices.Clone(cuckoo.slots)
+		c.cuckoo = &cuckoo
+	}
+	if i.filter != nil {
+		filter := *i.filter
+		filter.bits = slices.Clone(filter.bits)
+		c.filter = &filter
+	}
+	c.probes = probeStats{sampleEvery: i.probes.sampleEvery}
+	c.onInsert, c.onEvict = nil, nil
+	c.profile = nil
+	c.recorder = nil
+	c.debug = nil
+	if i.fold != nil {
+		c.fold = i.fold.clone()
+	}
+	c.dict, c.codes = nil, nil
+	c.shared = false
+	c.tuples, c.tupleSlices, c.scratch = nil, nil, nil
+	c.pinned = Set{}
+	c.pinned.Union(&i.pinned)
+	if i.compact != nil {
+		// The copy carries on with the compaction by itself
+		compact := *i.compact
+		compact.live = Set{}
+		compact.live.Union(&i.compact.live)
+		compact.moved = nil
+		c.compact = &compact
+	}
+	return &c
+}
+
+// sharedArena returns an arena that refers to the same strings as i's. Strings saved
+// to it go into a new chunk, as the rest of i's current chunk is left for i.
+func (i *Intern) sharedArena() arena {
+	a := i.arena
+	a.chunks = slices.Clip(a.chunks)
+	a.current = nil
+	return a
+}
@@ -0,0 +1,108 @@
+package intern_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnion(t *testing.T) {
+	a := intern.New(16, intern.WithBloomFilter())
+	b := intern.New(16)
+	offsets := make(map[string]int)
+	for j := 0; j < 1000; j++ {
+		offsets[strconv.Itoa(j)] = a.Save(strconv.Itoa(j))
+		b.Save(strconv.Itoa(j + 500))
+	}
+
+	u := intern.Union(a, b)
+	assert.Equal(t, 1500, u.Len())
+	for j := 0; j < 1500; j++ {
+		offset, ok := u.Lookup(strconv.Itoa(j))
+		assert.True(t, ok)
+		if j < 1000 {
+			// Strings from a keep their offsets, and share a's memory
+			assert.Equal(t, offsets[strconv.Itoa(j)], offset)
+			assert.Equal(t, datapointer(a.Get(offset)), datapointer(u.Get(offset)))
+		}
+	}
+
+	// Both can carry on independently
+	hat := a.Save("hat")
+	sat := u.Save("sat")
+	assert.Equal(t, "hat", a.Get(hat))
+	assert.Equal(t, "sat", u.Get(sat))
+	_, ok := a.Lookup("sat")
+	assert.False(t, ok)
+	_, ok = u.Lookup("hat")
+	assert.False(t, ok)
+	assert.Equal(t, 1001, a.Len())
+}
+
+func TestUnionLimits(t *testing.T) {
+	// Each fits within its limits, but the union of the two would not
+	a := intern.New(16, intern.WithMaxEntries(100), intern.WithMaxBytes(1<<20))
+	b := intern.New(16)
+	for j := 0; j < 100; j++ {
+		a.Save(strconv.Itoa(j))
+		b.Save(strconv.Itoa(j + 100))
+	}
+
+	u := intern.Union(a, b)
+	assert.Equal(t, 200, u.Len())
+	_, err := a.TrySave("hat")
+	assert.Equal(t, intern.ErrFull, err)
+	_, err = u.TrySave("hat")
+	assert.NoError(t, err)
+}
+
+func TestIntersect(t *testing.T) {
+	a := intern.New(16, intern.WithCuckoo())
+	b := intern.New(16)
+	for j := 0; j < 1000; j++ {
+		a.Save(strconv.Itoa(j))
+		b.Save(strconv.Itoa(j + 500))
+	}
+
+	r := intern.Intersect(a, b)
+	assert.Equal(t, 500, r.Len())
+	for j := 0; j < 1500; j++ {
+		offset, ok := r.Lookup(strconv.Itoa(j))
+		assert.Equal(t, j >= 500 && j < 1000, ok, j)
+		if ok {
+			aOffset, _ := a.Lookup(strconv.Itoa(j))
+			assert.Equal(t, aOffset, offset)
+		}
+	}
+
+	// New strings are stored without disturbing a
+	hat := r.Save("hat")
+	assert.Equal(t, "hat", r.Get(hat))
+	assert.Equal(t, "999", a.Get(a.Save("999")))
+	assert.Equal(t, 501, r.Len())
+}
+
+func TestExportWhere(t *testing.T) {
+	var in intern.Intern
+	counts := make(map[int]int)
+	for j := 0; j < 10000; j++ {
+		counts[in.Save(strconv.Itoa(j%1000))]++
+		if j%3 == 0 {
+			counts[in.Save(strconv.Itoa(j%100))]++
+		}
+	}
+
+	common := in.ExportWhere(func(val string) bool {
+		offset, _ := in.Lookup(val)
+		return counts[offset] > 10
+	})
+	assert.Equal(t, 100, common.Len())
+	for offset, val := range common.All() {
+		assert.Equal(t, in.Save(val), offset)
+		assert.True(t, counts[offset] > 10)
+	}
+
+	assert.Zero(t, in.ExportWhere(func(string) bool { return false }).Len())
+}
@@ -0,0 +1,300 @@
+package intern
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"runtime/trace"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+// ErrFrozen is returned when trying to store a new string in a frozen Shared interner
+var ErrFrozen = errors.New("intern: interner is frozen")
+
+// Shared is an interner that is safe for concurrent use. It wraps an Intern with a
+// mutex. The zero value is ready to use.
+type Shared struct {
+	mu sync.Mutex
+	in Intern
+	// migrating is set while a goroutine is copying entries to a new table
+	migrating bool
+	// frozen is set by Freeze. It is read without holding the lock
+	frozen atomic.Pointer[Snapshot]
+}
+
+// sharedMigrateBatch is the number of slots the background resize copies each time it
+// takes the lock
+const sharedMigrateBatch = 1024
+
+// NewShared creates a new Shared interner. The parameters are as for New.
+func NewShared(cap int, opts ...Option) *Shared {
+	s := &Shared{}
+	s.in = *New(cap, opts...)
+	return s
+}
+
+// Deduplicate is like Intern.Deduplicate
+func (s *Shared) Deduplicate(val string) string {
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.deduplicate(val)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Freeze may have been called while we waited for the lock
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.deduplicate(val)
+	}
+	defer s.startMigration()
+	return s.in.Deduplicate(val)
+}
+
+// DeduplicateBytes is like Intern.DeduplicateBytes
+func (s *Shared) DeduplicateBytes(b []byte) string {
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.deduplicateBytes(b)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.deduplicateBytes(b)
+	}
+	defer s.startMigration()
+	return s.in.DeduplicateBytes(b)
+}
+
+// TryDeduplicate is like Intern.TryDeduplicate. It returns ErrFrozen for a new string
+// while the interner is frozen.
+func (s *Shared) TryDeduplicate(val string) (string, error) {
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.tryDeduplicate(val)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Freeze may have been called while we waited for the lock
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.tryDeduplicate(val)
+	}
+	defer s.startMigration()
+	return s.in.TryDeduplicate(val)
+}
+
+// DeduplicateInPlace is like Intern.DeduplicateInPlace. The lock is taken once for the
+// whole batch.
+func (s *Shared) DeduplicateInPlace(vals []string) {
+	if snap := s.frozen.Load(); snap != nil {
+		snap.deduplicateInPlace(vals)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snap := s.frozen.Load(); snap != nil {
+		snap.deduplicateInPlace(vals)
+		return
+	}
+	defer s.startMigration()
+	s.in.DeduplicateInPlace(vals)
+}
+
+// DeduplicatePair is like Intern.DeduplicatePair. The lock is taken once for both
+// strings.
+func (s *Shared) DeduplicatePair(k, v string) (string, string) {
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.deduplicate(k), snap.deduplicate(v)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Freeze may have been called while we waited for the lock
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.deduplicate(k), snap.deduplicate(v)
+	}
+	defer s.startMigration()
+	return s.in.DeduplicatePair(k, v)
+}
+
+// Save is like Intern.Save
+func (s *Shared) Save(val string) int {
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.save(val)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.save(val)
+	}
+	defer s.startMigration()
+	return s.in.Save(val)
+}
+
+// SavePair is like Intern.SavePair. The lock is taken once for both strings.
+func (s *Shared) SavePair(k, v string) (ko, vo int) {
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.save(k), snap.save(v)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.save(k), snap.save(v)
+	}
+	defer s.startMigration()
+	return s.in.SavePair(k, v)
+}
+
+// TrySave is like Intern.TrySave. It returns InvalidOffset and ErrFrozen for a new
+// string while the interner is frozen.
+func (s *Shared) TrySave(val string) (int, error) {
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.trySave(val)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.trySave(val)
+	}
+	defer s.startMigration()
+	return s.in.TrySave(val)
+}
+
+// Get is like Intern.Get
+func (s *Shared) Get(offset int) string {
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.Get(offset)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.in.Get(offset)
+}
+
+// Len returns the number of unique strings stored
+func (s *Shared) Len() int {
+	if snap := s.frozen.Load(); snap != nil {
+		return snap.Len()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.in.Len()
+}
+
+// Collect is like Intern.Collect. It does nothing while the interner is frozen, and
+// returns nil.
+func (s *Shared) Collect(live iter.Seq[int]) map[int]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.frozen.Load() != nil {
+		return nil
+	}
+	return s.in.Collect(live)
+}
+
+// Evict is like Intern.Evict. It does nothing while the interner is frozen, and returns
+// nil.
+func (s *Shared) Evict(n int) map[int]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.frozen.Load() != nil {
+		return nil
+	}
+	return s.in.Evict(n)
+}
+
+// Freeze stops new strings being stored, which lets every method run without taking
+// the lock. This suits services that load their strings while warming up and then only
+// look them up: once frozen, Deduplicate returns new strings as it is given them, TrySave
+// returns ErrFrozen and Save panics. Thaw undoes Freeze.
+func (s *Shared) Freeze() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Lookups are quickest without a resize in progress
+	s.in.finishMigration()
+	s.frozen.Store(s.in.Snapshot())
+}
+
+// Thaw allows new strings to be stored again after Freeze
+func (s *Shared) Thaw() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.frozen.Store(nil)
+}
+
+// deduplicate is Shared.Deduplicate for a frozen interner
+func (s *Snapshot) deduplicate(val string) string {
+	if offset, ok := s.Lookup(val); ok {
+		return s.Get(offset)
+	}
+	return val
+}
+
+// tryDeduplicate is Shared.TryDeduplicate for a frozen interner
+func (s *Snapshot) tryDeduplicate(val string) (string, error) {
+	if offset, ok := s.Lookup(val); ok {
+		return s.Get(offset), nil
+	}
+	return val, ErrFrozen
+}
+
+// deduplicateBytes is Shared.DeduplicateBytes for a frozen interner
+func (s *Snapshot) deduplicateBytes(b []byte) string {
+	if offset, ok := s.Lookup(unsafe.String(unsafe.SliceData(b), len(b))); ok {
+		return s.Get(offset)
+	}
+	return string(b)
+}
+
+// deduplicateInPlace is Shared.DeduplicateInPlace for a frozen interner
+func (s *Snapshot) deduplicateInPlace(vals []string) {
+	for j, val := range vals {
+		if offset, ok := s.Lookup(val); ok {
+			vals[j] = s.Get(offset)
+		}
+	}
+}
+
+// trySave is Shared.TrySave for a frozen interner
+func (s *Snapshot) trySave(val string) (int, error) {
+	if offset, ok := s.Lookup(val); ok {
+		return offset, nil
+	}
+	return InvalidOffset, ErrFrozen
+}
+
+// save is Shared.Save for a frozen interner
+func (s *Snapshot) save(val string) int {
+	offset, err := s.trySave(val)
+	if err != nil {
+		panic(err)
+	}
+	return offset
+}
+
+// startMigration starts a goroutine to complete a resize if the Intern was created with
+// WithBackgroundResize and one is needed. s must be locked.
+func (s *Shared) startMigration() {
+	if s.in.deferMigration && s.in.oldTable.len() != 0 && !s.migrating {
+		s.migrating = true
+		go s.migrate()
+	}
+}
+
+// migrate copies entries to the new table a batch at a time, releasing the lock between
+// batches so that callers aren't held up for long.
+func (s *Shared) migrate() {
+	ctx, task := trace.NewTask(context.Background(), "intern.backgroundResize")
+	defer task.End()
+	for {
+		s.mu.Lock()
+		r := trace.StartRegion(ctx, "intern.migrate")
+		for k := 0; k < sharedMigrateBatch/16 && s.in.oldTable.len() != 0; k++ {
+			s.in.migrate()
+		}
+		done := s.in.oldTable.len() == 0
+		if done {
+			s.migrating = false
+		}
+		r.End()
+		s.mu.Unlock()
+		if done {
+			return
+		}
+	}
+}
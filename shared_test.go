@@ -0,0 +1,185 @@
+package intern_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShared(t *testing.T) {
+	s := intern.NewShared(16, intern.WithBloomFilter())
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				val := strconv.Itoa(j)
+				assert.Equal(t, val, s.Deduplicate(val))
+				offset, err := s.TrySave(val)
+				assert.NoError(t, err)
+				assert.Equal(t, val, s.Get(offset))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1000, s.Len())
+	assert.Equal(t, s.Save("1"), s.Save("1"))
+}
+
+func TestSharedZero(t *testing.T) {
+	var s intern.Shared
+	vals := []string{"hat", "sat", "hat"}
+	s.DeduplicateInPlace(vals)
+	assert.Equal(t, datapointer(vals[0]), datapointer(vals[2]))
+	assert.Equal(t, 2, s.Len())
+}
+
+func TestSharedBackgroundResize(t *testing.T) {
+	s := intern.NewShared(16, intern.WithBackgroundResize())
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50000; j++ {
+				val := strconv.Itoa(j)
+				assert.Equal(t, val, s.Get(s.Save(val)))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 50000, s.Len())
+	for j := 0; j < 50000; j++ {
+		assert.Equal(t, s.Save(strconv.Itoa(j)), s.Save(strconv.Itoa(j)))
+	}
+}
+
+func TestSharedFreeze(t *testing.T) {
+	var s intern.Shared
+	hat := s.Save("hat")
+	s.Freeze()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, hat, s.Save("hat"))
+			assert.Equal(t, "hat", s.Get(hat))
+			assert.Equal(t, datapointer(s.Get(hat)), datapointer(s.Deduplicate("hat")))
+
+			sat := "sat"
+			assert.Equal(t, datapointer(sat), datapointer(s.Deduplicate(sat)))
+			offset, err := s.TrySave(sat)
+			assert.Equal(t, intern.ErrFrozen, err)
+			assert.Equal(t, intern.InvalidOffset, offset)
+			assert.Panics(t, func() { s.Save(sat) })
+
+			vals := []string{"hat", sat}
+			s.DeduplicateInPlace(vals)
+			assert.Equal(t, datapointer(s.Get(hat)), datapointer(vals[0]))
+			assert.Equal(t, datapointer(sat), datapointer(vals[1]))
+			assert.Equal(t, 1, s.Len())
+		}()
+	}
+	wg.Wait()
+
+	s.Thaw()
+	sat, err := s.TrySave("sat")
+	assert.NoError(t, err)
+	assert.Equal(t, "sat", s.Get(sat))
+	assert.Equal(t, 2, s.Len())
+}
+
+func TestSharedTryDeduplicate(t *testing.T) {
+	var s intern.Shared
+	hat, err := s.TryDeduplicate("hat")
+	assert.NoError(t, err)
+
+	s.Freeze()
+	again, err := s.TryDeduplicate("hat")
+	assert.NoError(t, err)
+	assert.Equal(t, datapointer(hat), datapointer(again))
+	got, err := s.TryDeduplicate("sat")
+	assert.Equal(t, intern.ErrFrozen, err)
+	assert.Equal(t, "sat", got)
+}
+
+func TestSharedEvictFrozen(t *testing.T) {
+	s := intern.NewShared(16)
+	s.Save("hat")
+	s.Freeze()
+	assert.Nil(t, s.Evict(1))
+	assert.Nil(t, s.Collect(func(yield func(int) bool) {}))
+	assert.Equal(t, 1, s.Len())
+	s.Thaw()
+
+	assert.Len(t, s.Evict(1), 0)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestSharedDeduplicatePair(t *testing.T) {
+	var s intern.Shared
+	k, v := s.DeduplicatePair("key", "value")
+	assert.Equal(t, "key", k)
+	assert.Equal(t, "value", v)
+	ko, vo := s.SavePair("key", "value")
+	assert.Equal(t, k, s.Get(ko))
+	assert.Equal(t, v, s.Get(vo))
+
+	s.Freeze()
+	k, v = s.DeduplicatePair("key", "other")
+	assert.Equal(t, datapointer(s.Get(ko)), datapointer(k))
+	assert.Equal(t, "other", v)
+	assert.Equal(t, 2, s.Len())
+}
+
+func TestSharedFreezeRace(t *testing.T) {
+	s := intern.NewShared(16)
+
+	type saved struct {
+		val    string
+		offset int
+	}
+	results := make([][]saved, 8)
+	var wg sync.WaitGroup
+	for g := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20000; j++ {
+				val := strconv.Itoa(g) + "/" + strconv.Itoa(j)
+				if g%2 == 1 {
+					// TryDeduplicate doesn't give the offset, so only the count is checked
+					if _, err := s.TryDeduplicate(val); err == nil {
+						results[g] = append(results[g], saved{val, intern.InvalidOffset})
+					}
+				} else if offset, err := s.TrySave(val); err == nil {
+					results[g] = append(results[g], saved{val, offset})
+				}
+			}
+		}()
+	}
+	for s.Len() < 1000 {
+	}
+	s.Freeze()
+	wg.Wait()
+
+	// Every string stored is in the frozen interner
+	var n int
+	for _, r := range results {
+		for _, sv := range r {
+			if sv.offset != intern.InvalidOffset {
+				assert.Equal(t, sv.val, s.Get(sv.offset))
+			}
+		}
+		n += len(r)
+	}
+	assert.Equal(t, s.Len(), n)
+}
@@ -0,0 +1,25 @@
+package intern
+
+// StringSink is the one method a serialization library needs to intern the strings it
+// decodes. A library can accept a StringSink in its decoder options and turn each
+// string's bytes into a string through it, without depending on this package. Intern
+// and Shared implement it.
+type StringSink interface {
+	// InternString returns a string with the same contents as s. It must not keep s.
+	InternString(s []byte) string
+}
+
+var (
+	_ StringSink = (*Intern)(nil)
+	_ StringSink = (*Shared)(nil)
+)
+
+// InternString is DeduplicateBytes under the name StringSink expects
+func (i *Intern) InternString(s []byte) string {
+	return i.DeduplicateBytes(s)
+}
+
+// InternString is DeduplicateBytes under the name StringSink expects
+func (s *Shared) InternString(b []byte) string {
+	return s.DeduplicateBytes(b)
+}
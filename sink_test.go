@@ -0,0 +1,38 @@
+package intern_test
+
+import (
+	"testing"
+	"unsafe"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+// decodeWords stands in for a codec that accepts a StringSink
+func decodeWords(sink intern.StringSink, data []byte) []string {
+	var words []string
+	for len(data) != 0 {
+		l := int(data[0])
+		words = append(words, sink.InternString(data[1:1+l]))
+		data = data[1+l:]
+	}
+	return words
+}
+
+func TestStringSink(t *testing.T) {
+	data := []byte("\x03hat\x03cat\x03hat")
+	for name, sink := range map[string]intern.StringSink{
+		"intern": intern.New(16),
+		"shared": &intern.Shared{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			words := decodeWords(sink, data)
+			assert.Equal(t, []string{"hat", "cat", "hat"}, words)
+			assert.Equal(t, unsafe.StringData(words[0]), unsafe.StringData(words[2]))
+			// The strings don't share memory with the data
+			data[1] = 'b'
+			assert.Equal(t, "hat", words[0])
+			data[1] = 'h'
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package intern
+
+import (
+	"iter"
+	"slices"
+)
+
+// Snapshot is a read-only view of an Intern as it was when Snapshot was called. Any
+// number of goroutines may query a Snapshot without locking while a single writer
+// carries on saving strings to the Intern. Offsets are shared: an offset from the
+// Snapshot is valid in the Intern, and an offset the Intern had handed out when the
+// Snapshot was taken is valid in the Snapshot.
+type Snapshot struct {
+	in Intern
+}
+
+// Snapshot returns a read-only view of the strings stored so far. Taking a snapshot is
+// cheap, as the snapshot shares the Intern's hash table and strings. Strings are never
+// moved or overwritten, so they can stay shared, but the next time the Intern stores a
+// new string it first takes its own copy of the table.
+func (i *Intern) Snapshot() *Snapshot {
+	i.shared = true
+	s := &Snapshot{in: *i}
+	// The filter and probe statistics are updated in place, so the snapshot does without
+	s.in.filter = nil
+	s.in.probes = probeStats{}
+	s.in.onInsert, s.in.onEvict = nil, nil
+	s.in.profile = nil
+	s.in.recorder = nil
+	s.in.debug = nil
+	s.in.fold = nil
+	s.in.dict = nil
+	s.in.codes = nil
+	s.in.pinned = Set{}
+	return s
+}
+
+// Len returns the number of unique strings in the snapshot
+func (s *Snapshot) Len() int {
+	return s.in.Len()
+}
+
+// Get returns the string stored at offset
+func (s *Snapshot) Get(offset int) string {
+	return s.in.Get(offset)
+}
+
+// Lookup is like Intern.Lookup
+func (s *Snapshot) Lookup(val string) (offset int, ok bool) {
+	return s.in.find(val)
+}
+
+// All is like Intern.All
+func (s *Snapshot) All() iter.Seq2[int, string] {
+	return s.in.All()
+}
+
+// unshare gives the Intern its own copy of the hash table if a Snapshot shares it. It
+// must be called before the table is modified.
+func (i *Intern) unshare() {
+	if !i.shared {
+		return
+	}
+	i.shared = false
+	i.table.slots = slices.Clone(i.table.slots)
+	if i.cuckoo != nil {
+		c := *i.cuckoo
+		c.slots = slices.Clone(c.slots)
+		i.cuckoo = &c
+	}
+}
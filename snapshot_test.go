@@ -0,0 +1,55 @@
+package intern_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	for _, opts := range [][]intern.Option{
+		nil,
+		{intern.WithCuckoo()},
+		{intern.WithBloomFilter(), intern.WithProbeStats(1)},
+	} {
+		in := intern.New(16, opts...)
+		for j := 0; j < 1000; j++ {
+			in.Save(strconv.Itoa(j))
+		}
+		snap := in.Snapshot()
+
+		var wg sync.WaitGroup
+		for g := 0; g < 4; g++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 1000; j++ {
+					offset, ok := snap.Lookup(strconv.Itoa(j))
+					assert.True(t, ok)
+					assert.Equal(t, strconv.Itoa(j), snap.Get(offset))
+				}
+				_, ok := snap.Lookup("1000")
+				assert.False(t, ok)
+				assert.Equal(t, 1000, snap.Len())
+			}()
+		}
+
+		// The writer carries on while the readers use the snapshot
+		for j := 1000; j < 100000; j++ {
+			in.Save(strconv.Itoa(j))
+		}
+		wg.Wait()
+
+		assert.Equal(t, 100000, in.Len())
+		assert.Equal(t, 1000, snap.Len())
+		var count int
+		for offset, val := range snap.All() {
+			assert.Equal(t, offset, in.Save(val))
+			count++
+		}
+		assert.Equal(t, 1000, count)
+	}
+}
@@ -0,0 +1,76 @@
+package intern
+
+import "math/bits"
+
+// Stats describes the state of an Intern
+type Stats struct {
+	// Len is the number of unique strings stored
+	Len int
+	// Cap is the size of the hash table
+	Cap int
+	// GrowthThreshold is the load factor at which the table grows. It is 0.75 unless
+	// WithAdaptiveGrowth has adjusted it, or 0.875 with WithCuckoo
+	GrowthThreshold float64
+	// ProbeLengths is a histogram of the number of table slots examined by sampled
+	// lookups. ProbeLengths[k] counts lookups that examined at least 2^k and fewer than
+	// 2^(k+1) slots. It is only populated if the Intern was created with WithProbeStats.
+	ProbeLengths [32]uint64
+}
+
+// Stats returns statistics about the interner
+func (i *Intern) Stats() Stats {
+	return Stats{
+		Len:             i.count,
+		Cap:             i.Cap(),
+		GrowthThreshold: float64(i.loadLimit()) / 16,
+		ProbeLengths:    i.probes.lengths,
+	}
+}
+
+// Memory breaks down the memory used by an Intern, in bytes
+type Memory struct {
+	// Table is used by the hash table's hash and index arrays
+	Table int
+	// OldTable is used by the previous hash table while a resize is in progress
+	OldTable int
+	// Strings is allocated to hold the strings themselves, including space not yet used
+	Strings int
+	// Filter is used by the Bloom filter, if there is one
+	Filter int
+	// Tuples is used to store tuples saved with SaveTuple or DeduplicateSlice
+	Tuples int
+}
+
+// Total returns the total number of bytes used
+func (m Memory) Total() int {
+	return m.Table + m.OldTable + m.Strings + m.Filter + m.Tuples
+}
+
+// MemoryUsage reports the memory used by the hash tables and the string storage
+func (i *Intern) MemoryUsage() Memory {
+	return Memory{
+		Table:    i.table.bytes() + i.cuckoo.bytes(),
+		OldTable: i.oldTable.bytes(),
+		Strings:  i.arena.Size(),
+		Filter:   i.filter.bytes(),
+		Tuples:   i.tuples.memory(),
+	}
+}
+
+// probeStats records the lengths of probe sequences in the hash table
+type probeStats struct {
+	sampleEvery int
+	counter     int
+	lengths     [32]uint64
+}
+
+// record notes a lookup that examined the given number of slots. Cuckoo lookups count
+// the buckets examined instead
+func (p *probeStats) record(probes int) {
+	p.counter++
+	if p.counter < p.sampleEvery {
+		return
+	}
+	p.counter = 0
+	p.lengths[min(bits.Len(uint(probes))-1, len(p.lengths)-1)]++
+}
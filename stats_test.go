@@ -0,0 +1,64 @@
+package intern_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsProbeLengths(t *testing.T) {
+	in := intern.New(16, intern.WithProbeStats(1))
+	for j := 0; j < 1000; j++ {
+		in.Save(strconv.Itoa(j))
+	}
+
+	stats := in.Stats()
+	assert.Equal(t, 1000, stats.Len)
+	assert.Equal(t, 2048, stats.Cap)
+
+	var total uint64
+	for _, count := range stats.ProbeLengths {
+		total += count
+	}
+	// Every save probes the new table, and some probe the old one too during resizes
+	assert.True(t, total >= 1000, total)
+	// With a sensible load factor most probes should hit within a couple of slots
+	assert.True(t, stats.ProbeLengths[0]+stats.ProbeLengths[1] > total/2, stats.ProbeLengths)
+}
+
+func TestStatsProbeSampling(t *testing.T) {
+	in := intern.New(2048, intern.WithProbeStats(10))
+	for j := 0; j < 1000; j++ {
+		in.Save(strconv.Itoa(j))
+	}
+
+	var total uint64
+	for _, count := range in.Stats().ProbeLengths {
+		total += count
+	}
+	assert.Equal(t, uint64(100), total)
+}
+
+func TestStatsNoProbes(t *testing.T) {
+	in := intern.New(16)
+	in.Save("hat")
+	assert.Equal(t, [32]uint64{}, in.Stats().ProbeLengths)
+}
+
+func TestMemoryUsage(t *testing.T) {
+	in := intern.New(64)
+	assert.Equal(t, intern.Memory{Table: 64 * 16}, in.MemoryUsage())
+
+	for j := 0; j < 48; j++ {
+		in.Save(strconv.Itoa(j))
+	}
+	assert.Equal(t, intern.Memory{Table: 64 * 16, Strings: 1 << 18}, in.MemoryUsage())
+
+	// The next save starts a resize, which keeps the old table until it is complete
+	in.Save("resize")
+	m := in.MemoryUsage()
+	assert.Equal(t, intern.Memory{Table: 128 * 16, OldTable: 64 * 16, Strings: 1 << 18}, m)
+	assert.Equal(t, 192*16+1<<18, m.Total())
+}
@@ -0,0 +1,265 @@
+package intern
+
+import (
+	"math/bits"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// Striped is an interner that is safe for concurrent use. It is a middle ground between
+// Shared, where every call takes the same lock, and keeping a separate interner per
+// shard. The hash table is split into stripes, each with its own lock, and a string's hash
+// decides which stripe it belongs to, so concurrent calls for unrelated strings rarely
+// contend. All the strings are kept in one arena, so offsets come from a single space just
+// as with Intern.
+//
+// Readers don't take locks at all. Strings that are already present are found without
+// locking, and Get reads the arena directly. Writers publish each new entry with an atomic
+// store of its index, and when a stripe grows, its new table is built to one side and
+// swapped in atomically. Readers still probing the old table carry on undisturbed, and
+// the garbage collector frees it once they have all finished, so there is no need for
+// epochs to track when it is safe to reclaim.
+type Striped struct {
+	seed uint64
+	// shift turns a spread hash into a stripe number
+	shift   uint
+	stripes []stripe
+	count   atomic.Int64
+
+	arenaMu sync.Mutex
+	arena   arena
+	// chunks is a copy of arena.chunks that can be read without holding arenaMu. A
+	// reader may see an out of date list, but it will include the chunk holding any offset
+	// the reader has been given.
+	chunks atomic.Pointer[[][]byte]
+}
+
+// stripe is one part of a Striped interner's hash table. Stripes are small, so they
+// grow all at once rather than incrementally.
+type stripe struct {
+	mu    sync.Mutex
+	table atomic.Pointer[table]
+	count int
+	// locks counts the times the lock was taken, and waits the times that meant waiting
+	// for another goroutine. waitTime is the total time spent waiting
+	locks    uint64
+	waits    uint64
+	waitTime time.Duration
+	// pad keeps each stripe on its own cache line, so that locking one doesn't slow
+	// down its neighbours
+	_ [16]byte
+}
+
+// NewStriped creates a Striped interner with the given number of stripes, which is
+// rounded up to a power of 2. A few times GOMAXPROCS is a good choice. cap is the total
+// initial capacity, split between the stripes.
+func NewStriped(cap int, stripes int) *Striped {
+	n := 1 << bits.Len(uint(max(stripes, 1)-1))
+	l := max(16, 1<<bits.Len(uint(max(cap/n, 1)-1)))
+
+	s := &Striped{
+		seed:    rand.Uint64(),
+		shift:   uint(64 - bits.TrailingZeros(uint(n))),
+		stripes: make([]stripe, n),
+	}
+	for j := range s.stripes {
+		t := newTable(l, false)
+		s.stripes[j].table.Store(&t)
+	}
+	return s
+}
+
+// Deduplicate is like Intern.Deduplicate
+func (s *Striped) Deduplicate(val string) string {
+	return s.Get(s.Save(val))
+}
+
+// Save is like Intern.Save
+func (s *Striped) Save(val string) int {
+	hash := hashString(val, nil, s.seed)
+	spread := mix(hash ^ s.seed)
+	st := &s.stripes[spread>>s.shift]
+	if _, index := s.find(st.table.Load(), val, hash, spread); index != 0 {
+		return index - 1
+	}
+
+	st.lock()
+	defer st.mu.Unlock()
+	// Look again now we hold the lock, as another writer may have stored val
+	t := st.table.Load()
+	cursor, index := s.find(t, val, hash, spread)
+	if index != 0 {
+		return index - 1
+	}
+	if st.count >= t.len()*3/4 {
+		t = s.grow(st)
+		cursor, _ = s.find(t, val, hash, spread)
+	}
+
+	offset := s.save(val)
+	// Readers check the index before the hash, so the hash must be in place first
+	t.slots[cursor].hash = hash
+	atomic.StoreUintptr(slotIndex(&t.slots[cursor]), uintptr(offset+1))
+	st.count++
+	s.count.Add(1)
+	return offset
+}
+
+// Lookup is like Intern.Lookup
+func (s *Striped) Lookup(val string) (offset int, ok bool) {
+	hash := hashString(val, nil, s.seed)
+	spread := mix(hash ^ s.seed)
+	_, index := s.find(s.stripes[spread>>s.shift].table.Load(), val, hash, spread)
+	if index == 0 {
+		return InvalidOffset, false
+	}
+	return index - 1, true
+}
+
+// Get returns the string stored at offset
+func (s *Striped) Get(offset int) string {
+	return readString(*s.chunks.Load(), arenaChunkShift, offset)
+}
+
+// Len returns the number of unique strings stored
+func (s *Striped) Len() int {
+	return int(s.count.Load())
+}
+
+// find looks for val in a stripe's table. It is safe to call without holding the
+// stripe's lock. It returns where the string was found or should be stored, and the arena
+// offset of the string + 1 if it was found.
+func (s *Striped) find(t *table, val string, hash, spread uint64) (cursor int, index int) {
+	mask := t.len() - 1
+	for cursor = int(spread) & mask; ; cursor = (cursor + 1) & mask {
+		sl := &t.slots[cursor]
+		index := int(atomic.LoadUintptr(slotIndex(sl)))
+		if index == 0 {
+			return cursor, 0
+		}
+		if sl.hash == hash && (isInline(hash) || s.Get(index-1) == val) {
+			return cursor, index
+		}
+	}
+}
+
+// grow replaces a stripe's table with one twice the size, and returns it. The stripe
+// must be locked.
+func (s *Striped) grow(st *stripe) *table {
+	old := st.table.Load()
+	t := newTable(old.len()*2, false)
+	mask := t.len() - 1
+	for _, sl := range old.slots {
+		if sl.index == 0 {
+			continue
+		}
+		cursor := int(mix(sl.hash^s.seed)) & mask
+		for t.slots[cursor].index != 0 {
+			cursor = (cursor + 1) & mask
+		}
+		t.slots[cursor] = sl
+	}
+	st.table.Store(&t)
+	return &t
+}
+
+// slotIndex returns the index field of a slot so that it can be read and written
+// atomically. An int is always the same size as a uintptr
+func slotIndex(sl *slot) *uintptr {
+	return (*uintptr)(unsafe.Pointer(&sl.index))
+}
+
+// save copies val into the arena
+func (s *Striped) save(val string) int {
+	s.arenaMu.Lock()
+	defer s.arenaMu.Unlock()
+	if s.arena.growth(len(val)) != 0 && !s.arena.canGrow(len(val)) {
+		panic(ErrTooLarge)
+	}
+	n := len(s.arena.chunks)
+	offset := s.arena.save(val)
+	if len(s.arena.chunks) != n {
+		chunks := s.arena.chunks
+		s.chunks.Store(&chunks)
+	}
+	return offset
+}
+
+// lock locks the stripe, keeping track of how often and for how long it has to wait
+func (st *stripe) lock() {
+	if !st.mu.TryLock() {
+		start := time.Now()
+		st.mu.Lock()
+		st.waits++
+		st.waitTime += time.Since(start)
+	}
+	st.locks++
+}
+
+// StripedStats describes how the strings and the work are spread across a Striped
+// interner's stripes
+type StripedStats struct {
+	Stripes []StripeStats
+	// Imbalance is the number of strings in the fullest stripe divided by the mean. Close
+	// to 1 is good. Much larger, with plenty of strings stored, means the hash is
+	// distributing the strings badly.
+	Imbalance float64
+	// SuggestedStripes is a suggested number of stripes. It is double the current number
+	// if more than one write in ten had to wait for a lock
+	SuggestedStripes int
+}
+
+// StripeStats describes one stripe
+type StripeStats struct {
+	// Len is the number of strings in the stripe
+	Len int
+	// Cap is the size of the stripe's hash table
+	Cap int
+	// Locks is the number of times the stripe was locked to add a string
+	Locks uint64
+	// Waits is the number of those times the lock was held by another goroutine
+	Waits uint64
+	// WaitTime is the total time spent waiting for the lock
+	WaitTime time.Duration
+}
+
+// contendedWrites is the fraction of writes that may wait for a lock before
+// StripedStats suggests more stripes
+const contendedWrites = 0.1
+
+// Stats returns statistics about each stripe, to help spot skewed hash distributions and
+// lock contention
+func (s *Striped) Stats() StripedStats {
+	stats := StripedStats{Stripes: make([]StripeStats, len(s.stripes))}
+	var total, largest int
+	var locks, waits uint64
+	for j := range s.stripes {
+		st := &s.stripes[j]
+		st.mu.Lock()
+		stats.Stripes[j] = StripeStats{
+			Len:      st.count,
+			Cap:      st.table.Load().len(),
+			Locks:    st.locks,
+			Waits:    st.waits,
+			WaitTime: st.waitTime,
+		}
+		st.mu.Unlock()
+
+		total += st.count
+		largest = max(largest, st.count)
+		locks += stats.Stripes[j].Locks
+		waits += stats.Stripes[j].Waits
+	}
+
+	if total > 0 {
+		stats.Imbalance = float64(largest) * float64(len(s.stripes)) / float64(total)
+	}
+	stats.SuggestedStripes = len(s.stripes)
+	if locks > 0 && float64(waits)/float64(locks) > contendedWrites {
+		stats.SuggestedStripes *= 2
+	}
+	return stats
+}
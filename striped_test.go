@@ -0,0 +1,131 @@
+package intern_test
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStriped(t *testing.T) {
+	s := intern.NewStriped(16, 8)
+
+	var wg sync.WaitGroup
+	offsets := make([][]int, 8)
+	for g := range offsets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 30000; j++ {
+				val := strconv.Itoa(j)
+				offset := s.Save(val)
+				assert.Equal(t, val, s.Get(offset))
+				offsets[g] = append(offsets[g], offset)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 30000, s.Len())
+	for g := range offsets {
+		assert.Equal(t, offsets[0], offsets[g])
+	}
+	for j, offset := range offsets[0] {
+		val := strconv.Itoa(j)
+		assert.Equal(t, val, s.Get(offset))
+		assert.Equal(t, datapointer(s.Get(offset)), datapointer(s.Deduplicate(val)))
+	}
+}
+
+func TestStripedOneStripe(t *testing.T) {
+	s := intern.NewStriped(0, 0)
+	hat := s.Save("hat")
+	assert.Equal(t, hat, s.Save("hat"))
+	assert.Equal(t, "", s.Deduplicate(""))
+	assert.Equal(t, "hat", s.Get(hat))
+	assert.Equal(t, 2, s.Len())
+}
+
+func BenchmarkStriped(b *testing.B) {
+	vals := make([]string, 100000)
+	for j := range vals {
+		vals[j] = "value" + strconv.Itoa(j)
+	}
+
+	b.Run("shared", func(b *testing.B) {
+		s := intern.NewShared(16)
+		b.RunParallel(func(pb *testing.PB) {
+			for j := 0; pb.Next(); j++ {
+				s.Deduplicate(vals[j%len(vals)])
+			}
+		})
+	})
+	b.Run("striped", func(b *testing.B) {
+		s := intern.NewStriped(16, 64)
+		b.RunParallel(func(pb *testing.PB) {
+			for j := 0; pb.Next(); j++ {
+				s.Deduplicate(vals[j%len(vals)])
+			}
+		})
+	})
+}
+
+func TestStripedReadDuringGrowth(t *testing.T) {
+	s := intern.NewStriped(16, 2)
+	hat := s.Save("a hat that is long")
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				offset, ok := s.Lookup("a hat that is long")
+				assert.True(t, ok)
+				assert.Equal(t, hat, offset)
+			}
+		}()
+	}
+
+	// The readers keep finding the string while every stripe grows repeatedly
+	for j := 0; j < 100000; j++ {
+		s.Save(strconv.Itoa(j))
+	}
+	close(done)
+	wg.Wait()
+
+	_, ok := s.Lookup("missing")
+	assert.False(t, ok)
+}
+
+func TestStripedStats(t *testing.T) {
+	s := intern.NewStriped(16, 4)
+	for j := 0; j < 10000; j++ {
+		s.Save(strconv.Itoa(j))
+	}
+	s.Save("1")
+
+	stats := s.Stats()
+	assert.Len(t, stats.Stripes, 4)
+	var total int
+	var locks uint64
+	for _, st := range stats.Stripes {
+		total += st.Len
+		locks += st.Locks
+		assert.True(t, st.Len < st.Cap, st)
+		// Nothing else was running, so nothing had to wait
+		assert.Zero(t, st.Waits)
+	}
+	assert.Equal(t, 10000, total)
+	assert.Equal(t, uint64(10000), locks)
+	assert.True(t, stats.Imbalance >= 1 && stats.Imbalance < 1.1, stats.Imbalance)
+	assert.Equal(t, 4, stats.SuggestedStripes)
+}
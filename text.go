@@ -0,0 +1,107 @@
+package intern
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// WriteStrings writes every stored string to w, one per line, in the order AllInOrder
+// gives them. Backslashes, newlines and carriage returns in the strings are escaped as
+// \\, \n and \r, so every line holds exactly one string and the output can be inspected
+// with standard Unix tools. ReadStrings reads it back.
+func (i *Intern) WriteStrings(w io.Writer) error {
+	bw := bufio.NewWriterSize(w, 64*1024)
+	var line []byte
+	for _, val := range i.AllInOrder() {
+		line = appendEscaped(line[:0], val)
+		line = append(line, '\n')
+		if _, err := bw.Write(line); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// appendEscaped appends val to dst, escaping the characters that would break it across
+// lines
+func appendEscaped(dst []byte, val string) []byte {
+	for j := 0; j < len(val); j++ {
+		switch c := val[j]; c {
+		case '\\':
+			dst = append(dst, '\\', '\\')
+		case '\n':
+			dst = append(dst, '\\', 'n')
+		case '\r':
+			dst = append(dst, '\\', 'r')
+		default:
+			dst = append(dst, c)
+		}
+	}
+	return dst
+}
+
+// ReadStrings saves every line read from r, undoing the escaping done by WriteStrings.
+// A carriage return at the end of a line is dropped, as with bufio.ScanLines. As with
+// ReadFrom, reading the output of WriteStrings into an empty Intern made with the same
+// options gives every string the offset it had, unless StartCompaction had moved them.
+// Input is read through a large buffer, so this is a quick way to warm an Intern from a
+// text file.
+func (i *Intern) ReadStrings(r io.Reader) error {
+	br := bufio.NewReaderSize(r, 1024*1024)
+	var long, unescaped []byte
+	for {
+		line, err := br.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// The line is longer than the buffer, so gather it up
+			long = append(long, line...)
+			continue
+		}
+		if len(long) != 0 {
+			line = append(long, line...)
+			long = long[:0]
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(line) == 0 && err == io.EOF {
+			return nil
+		}
+
+		line = dropCR(bytes.TrimSuffix(line, []byte{'\n'}))
+		if bytes.IndexByte(line, '\\') >= 0 {
+			unescaped = appendUnescaped(unescaped[:0], line)
+			line = unescaped
+		}
+		if _, err := i.saveBytes(line); err != nil {
+			return err
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// appendUnescaped appends line to dst, undoing appendEscaped. Backslashes that don't
+// start a known escape are kept as they are.
+func appendUnescaped(dst []byte, line []byte) []byte {
+	for j := 0; j < len(line); j++ {
+		c := line[j]
+		if c == '\\' && j+1 < len(line) {
+			switch line[j+1] {
+			case '\\':
+				c = '\\'
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			default:
+				dst = append(dst, c)
+				continue
+			}
+			j++
+		}
+		dst = append(dst, c)
+	}
+	return dst
+}
@@ -0,0 +1,61 @@
+package intern_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteStrings(t *testing.T) {
+	var in intern.Intern
+	for _, val := range []string{"hat", "two\nlines", `back\slash`, "", "crlf\r\n"} {
+		in.Save(val)
+	}
+
+	var b strings.Builder
+	assert.NoError(t, in.WriteStrings(&b))
+	assert.Equal(t, "hat\ntwo\\nlines\nback\\\\slash\n\ncrlf\\r\\n\n", b.String())
+}
+
+func TestWriteStringsError(t *testing.T) {
+	var in intern.Intern
+	in.Save("hat")
+	assert.EqualError(t, in.WriteStrings(errWriter{}), "oops")
+}
+
+func TestReadStrings(t *testing.T) {
+	var in intern.Intern
+	vals := []string{"hat", "two\nlines", `back\slash`, "", "crlf\r\n", strings.Repeat("long", 50000)}
+	offsets := make([]int, len(vals))
+	for j, val := range vals {
+		offsets[j] = in.Save(val)
+	}
+
+	var b strings.Builder
+	assert.NoError(t, in.WriteStrings(&b))
+
+	var out intern.Intern
+	assert.NoError(t, out.ReadStrings(strings.NewReader(b.String())))
+	assert.Equal(t, len(vals), out.Len())
+	for j, val := range vals {
+		assert.Equal(t, val, out.Get(offsets[j]))
+	}
+}
+
+func TestReadStringsText(t *testing.T) {
+	var in intern.Intern
+	assert.NoError(t, in.ReadStrings(strings.NewReader("dos\r\nunix\nodd\\escape\nno newline")))
+
+	var vals []string
+	for _, val := range in.AllInOrder() {
+		vals = append(vals, val)
+	}
+	assert.Equal(t, []string{"dos", "unix", `odd\escape`, "no newline"}, vals)
+}
+
+func TestReadStringsError(t *testing.T) {
+	in := intern.New(16, intern.WithMaxBytes(1))
+	assert.Equal(t, intern.ErrMaxBytes, in.ReadStrings(strings.NewReader("hat\n")))
+}
@@ -0,0 +1,34 @@
+package intern_test
+
+import (
+	"bytes"
+	"io"
+	"runtime/trace"
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceRegions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Skipf("tracing unavailable: %v", err)
+	}
+
+	in := intern.New(16)
+	for j := range 1000 {
+		in.Save(strconv.Itoa(j))
+	}
+	in.Reserve(10000)
+	var live intern.Set
+	in.Collect(live.All())
+	_, err := in.WriteSnapshot(io.Discard)
+	trace.Stop()
+	assert.NoError(t, err)
+
+	for _, name := range []string{"intern.grow", "intern.reserve", "intern.rebuild", "intern.write"} {
+		assert.True(t, bytes.Contains(buf.Bytes(), []byte(name)), name)
+	}
+}
@@ -0,0 +1,73 @@
+package intern
+
+import "encoding/binary"
+
+// SaveTuple stores a sequence of strings as a unit and returns an offset for the whole
+// sequence. Each string is stored as by Save, and two calls return the same offset
+// exactly when they are given the same strings in the same order, so composite keys
+// such as sets of labels can be compared with a single integer. Tuple offsets come from
+// a separate space to string offsets: use GetTuple to turn one back into its strings.
+// SaveTuple panics if a string can't be stored within the memory budget.
+func (i *Intern) SaveTuple(vals []string) int {
+	offset, err := i.trySaveTuple(vals)
+	if err != nil {
+		panic(err)
+	}
+	return offset
+}
+
+// GetTuple returns the strings saved by SaveTuple at offset
+func (i *Intern) GetTuple(offset int) []string {
+	return i.AppendTuple(nil, offset)
+}
+
+// AppendTuple appends the strings saved by SaveTuple at offset to dst and returns the
+// extended slice
+func (i *Intern) AppendTuple(dst []string, offset int) []string {
+	key := i.tuples.Get(offset)
+	for len(key) > 0 {
+		v, n := binary.Uvarint([]byte(key[:min(len(key), binary.MaxVarintLen64)]))
+		dst = append(dst, i.Get(int(v)))
+		key = key[n:]
+	}
+	return dst
+}
+
+// DeduplicateSlice returns a permanently stored version of vals. Each string in it is
+// stored as by Deduplicate, and the slice itself is shared by every call with the same
+// strings in the same order, so the result must not be modified. If the strings can't be
+// stored within the memory budget vals is returned as it is.
+func (i *Intern) DeduplicateSlice(vals []string) []string {
+	offset, err := i.trySaveTuple(vals)
+	if err != nil {
+		return vals
+	}
+	if stored, ok := i.tupleSlices[offset]; ok {
+		return stored
+	}
+	stored := i.GetTuple(offset)
+	if i.tupleSlices == nil {
+		i.tupleSlices = make(map[int][]string)
+	}
+	i.tupleSlices[offset] = stored
+	return stored
+}
+
+// trySaveTuple stores vals as a tuple. The tuple is kept as a string of the offsets of its
+// members in a second Intern
+func (i *Intern) trySaveTuple(vals []string) (int, error) {
+	key := i.scratch[:0]
+	for _, val := range vals {
+		offset, err := i.TrySave(val)
+		if err != nil {
+			return 0, err
+		}
+		key = binary.AppendUvarint(key, uint64(offset))
+	}
+	i.scratch = key
+
+	if i.tuples == nil {
+		i.tuples = New(16)
+	}
+	return i.tuples.saveBytes(key)
+}
@@ -0,0 +1,50 @@
+package intern_test
+
+import (
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveTuple(t *testing.T) {
+	var in intern.Intern
+
+	a := in.SaveTuple([]string{"job", "api", "env", "prod"})
+	b := in.SaveTuple([]string{"job", "api", "env", "dev"})
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, a, in.SaveTuple([]string{"job", "api", "env", "prod"}))
+	// Order matters
+	assert.NotEqual(t, a, in.SaveTuple([]string{"env", "prod", "job", "api"}))
+
+	assert.Equal(t, []string{"job", "api", "env", "prod"}, in.GetTuple(a))
+	assert.Equal(t, []string{"x", "job", "api", "env", "dev"}, in.AppendTuple([]string{"x"}, b))
+
+	empty := in.SaveTuple(nil)
+	assert.Equal(t, empty, in.SaveTuple([]string{}))
+	assert.Empty(t, in.GetTuple(empty))
+
+	// Members are stored as ordinary strings too
+	assert.Equal(t, 5, in.Len())
+	assert.NotZero(t, in.MemoryUsage().Tuples)
+}
+
+func TestDeduplicateSlice(t *testing.T) {
+	var in intern.Intern
+
+	a := in.DeduplicateSlice([]string{"hat", "sat"})
+	b := in.DeduplicateSlice([]string{"hat", "sat"})
+	assert.Equal(t, []string{"hat", "sat"}, a)
+	assert.Equal(t, &a[0], &b[0])
+	assert.Equal(t, datapointer(in.Deduplicate("hat")), datapointer(a[0]))
+
+	c := in.DeduplicateSlice([]string{"sat", "hat"})
+	assert.NotEqual(t, &a[0], &c[0])
+}
+
+func TestDeduplicateSliceMaxBytes(t *testing.T) {
+	in := intern.New(16, intern.WithMaxBytes(1))
+	vals := []string{"hat"}
+	assert.Equal(t, &vals[0], &in.DeduplicateSlice(vals)[0])
+	assert.Panics(t, func() { in.SaveTuple(vals) })
+}
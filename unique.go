@@ -0,0 +1,17 @@
+package intern
+
+import "unique"
+
+// HandleFor returns the unique.Handle for the string stored at offset. This helps code
+// that is moving between this package and the standard library's unique package work
+// with both. The handle's string is canonicalised by the unique package, so is not backed
+// by the same memory as the string in the arena.
+func (i *Intern) HandleFor(offset int) unique.Handle[string] {
+	return unique.Make(i.Get(offset))
+}
+
+// SaveHandle stores the string h refers to and returns its offset. Like Save, it panics
+// if the string is new and storing it would exceed the memory budget.
+func (i *Intern) SaveHandle(h unique.Handle[string]) int {
+	return i.Save(h.Value())
+}
@@ -0,0 +1,23 @@
+package intern_test
+
+import (
+	"testing"
+	"unique"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandles(t *testing.T) {
+	var in intern.Intern
+	hat := in.Save("hat")
+
+	h := in.HandleFor(hat)
+	assert.Equal(t, unique.Make("hat"), h)
+	assert.Equal(t, "hat", h.Value())
+	assert.Equal(t, hat, in.SaveHandle(h))
+
+	sat := in.SaveHandle(unique.Make("sat"))
+	assert.Equal(t, "sat", in.Get(sat))
+	assert.Equal(t, 2, in.Len())
+}
@@ -0,0 +1,32 @@
+package intern
+
+// DeduplicateValue interns every string in v, which is typically the result of decoding
+// a document into an any, as encoding/json, YAML and Avro libraries do. Strings are
+// found in maps with string or any keys, including the keys themselves, and in slices
+// of any. Maps and slices are updated in place, and the result is v with any strings
+// replaced. Other values are left alone.
+func (i *Intern) DeduplicateValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return i.Deduplicate(v)
+	case map[string]any:
+		for k, e := range v {
+			// Assigning with an equal key replaces the key stored in the map
+			v[i.Deduplicate(k)] = i.DeduplicateValue(e)
+		}
+	case map[any]any:
+		for k, e := range v {
+			if s, ok := k.(string); ok {
+				k = i.Deduplicate(s)
+			}
+			v[k] = i.DeduplicateValue(e)
+		}
+	case []any:
+		for j, e := range v {
+			v[j] = i.DeduplicateValue(e)
+		}
+	case []string:
+		i.DeduplicateInPlace(v)
+	}
+	return v
+}
@@ -0,0 +1,38 @@
+package intern_test
+
+import (
+	"encoding/json"
+	"testing"
+	"unsafe"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeduplicateValue(t *testing.T) {
+	in := intern.New(16)
+	var doc any
+	assert.NoError(t, json.Unmarshal([]byte(`[{"kind":"Pod","spec":{"kind":"x"}},{"kind":"Pod","n":3,"tags":["Pod",null]}]`), &doc))
+	doc = in.DeduplicateValue(doc)
+	assert.Equal(t, 6, in.Len())
+
+	items := doc.([]any)
+	a, b := items[0].(map[string]any), items[1].(map[string]any)
+	assert.Equal(t, unsafe.StringData(a["kind"].(string)), unsafe.StringData(b["kind"].(string)))
+	assert.Equal(t, unsafe.StringData(a["kind"].(string)), unsafe.StringData(b["tags"].([]any)[0].(string)))
+	assert.Equal(t, float64(3), b["n"])
+	for k := range b {
+		stored, ok := in.Lookup(k)
+		assert.True(t, ok)
+		assert.Equal(t, unsafe.StringData(in.Get(stored)), unsafe.StringData(k))
+	}
+
+	// YAML decoders produce maps with keys of any type
+	m := map[any]any{"replicas": 3, 7: "Pod", "names": []string{"Pod", "Service"}}
+	in.DeduplicateValue(m)
+	assert.Equal(t, unsafe.StringData(a["kind"].(string)), unsafe.StringData(m[7].(string)))
+	assert.Equal(t, unsafe.StringData(a["kind"].(string)), unsafe.StringData(m["names"].([]string)[0]))
+	assert.Equal(t, 9, in.Len())
+
+	assert.Equal(t, 42, in.DeduplicateValue(42))
+}
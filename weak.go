@@ -0,0 +1,102 @@
+package intern
+
+import (
+	"hash/maphash"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+	"weak"
+)
+
+// Weak is a string interner that holds its strings weakly. Unlike Intern, strings are
+// not kept in an arena: each one is a normal Go allocation, and once no string
+// returned by Deduplicate refers to it any more the garbage collector is free to reclaim
+// it, and the entry is dropped from the table.
+//
+// Weak is safe for concurrent use. The zero value is ready to use.
+type Weak struct {
+	mu      sync.Mutex
+	seed    maphash.Seed
+	entries map[uint64][]weakEntry
+	count   int
+}
+
+// weakEntry refers to the bytes of a stored string without keeping them alive
+type weakEntry struct {
+	data weak.Pointer[byte]
+	len  int
+}
+
+// weakKey identifies an entry to remove once its string has been collected
+type weakKey struct {
+	hash uint64
+	data weak.Pointer[byte]
+}
+
+// Deduplicate returns a canonical version of val. While any string returned by
+// Deduplicate for a given value is still reachable, every call with that value returns a
+// string backed by the same memory.
+func (w *Weak) Deduplicate(val string) string {
+	if len(val) == 0 {
+		return ""
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.entries == nil {
+		w.seed = maphash.MakeSeed()
+		w.entries = make(map[uint64][]weakEntry)
+	}
+
+	hash := maphash.String(w.seed, val)
+	for _, e := range w.entries[hash] {
+		if e.len != len(val) {
+			continue
+		}
+		if data := e.data.Value(); data != nil {
+			if stored := unsafe.String(data, e.len); stored == val {
+				return stored
+			}
+		}
+	}
+
+	// We need our own copy, both so it isn't shared with anything else the caller's
+	// string is part of, and so the cleanup runs when our copy is no longer used.
+	stored := strings.Clone(val)
+	data := unsafe.StringData(stored)
+	key := weakKey{hash: hash, data: weak.Make(data)}
+	w.entries[hash] = append(w.entries[hash], weakEntry{data: key.data, len: len(stored)})
+	w.count++
+	runtime.AddCleanup(data, w.remove, key)
+
+	return stored
+}
+
+// Len returns the number of strings currently held. Strings that have been collected may
+// be counted until the runtime gets round to removing their entries.
+func (w *Weak) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.count
+}
+
+// remove is called by the runtime once the string for an entry is unreachable
+func (w *Weak) remove(key weakKey) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entries := w.entries[key.hash]
+	for j, e := range entries {
+		if e.data == key.data {
+			entries = append(entries[:j], entries[j+1:]...)
+			w.count--
+			break
+		}
+	}
+	if len(entries) == 0 {
+		delete(w.entries, key.hash)
+	} else {
+		w.entries[key.hash] = entries
+	}
+}
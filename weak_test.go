@@ -0,0 +1,64 @@
+package intern_test
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeak(t *testing.T) {
+	var w intern.Weak
+
+	hat := w.Deduplicate(strings.Repeat("hat", 10))
+	hat2 := w.Deduplicate(strings.Repeat("hat", 10))
+	sat := w.Deduplicate(strings.Repeat("sat", 10))
+
+	assert.Equal(t, strings.Repeat("hat", 10), hat)
+	assert.Equal(t, strings.Repeat("sat", 10), sat)
+	assert.Equal(t, datapointer(hat), datapointer(hat2))
+	assert.Equal(t, 2, w.Len())
+	assert.Equal(t, "", w.Deduplicate(""))
+
+	runtime.KeepAlive(hat)
+	runtime.KeepAlive(sat)
+}
+
+func TestWeakCollect(t *testing.T) {
+	var w intern.Weak
+
+	keep := w.Deduplicate("this string is kept alive")
+	for j := 0; j < 1000; j++ {
+		w.Deduplicate("this string is thrown away " + strconv.Itoa(j))
+	}
+
+	// Cleanups run asynchronously after the GC finds the strings unreachable
+	for start := time.Now(); w.Len() > 1 && time.Since(start) < 5*time.Second; {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 1, w.Len())
+	assert.Equal(t, datapointer(keep), datapointer(w.Deduplicate("this string is kept alive")))
+	runtime.KeepAlive(keep)
+}
+
+func TestWeakConcurrent(t *testing.T) {
+	var w intern.Weak
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10000; j++ {
+				val := "concurrent value " + strconv.Itoa(j%100)
+				assert.Equal(t, val, w.Deduplicate(val))
+			}
+		}()
+	}
+	wg.Wait()
+}
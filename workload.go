@@ -0,0 +1,230 @@
+package intern
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// A recording of a workload is a short header followed by one record for each string
+// saved or looked up, in the order the calls were made:
+//
+//	header:  "IREC", a version byte (1) and a RecordMode byte
+//	record:  an op byte (1 save, 2 lookup), then for RecordFull the string's length as
+//	         a uvarint and its bytes, or for RecordHashed its XXH64 hash (seed 0) as a
+//	         little-endian uint64 and its length as a uvarint
+
+const (
+	workloadMagic   = "IREC"
+	workloadVersion = 1
+
+	opSave   = 1
+	opLookup = 2
+)
+
+// ErrWorkload is returned when a recorded workload can't be read
+var ErrWorkload = errors.New("intern: invalid workload recording")
+
+// RecordMode chooses how much of each string a Recorder keeps
+type RecordMode int
+
+const (
+	// RecordFull keeps every string as it was
+	RecordFull RecordMode = iota
+	// RecordHashed keeps only the hash and length of each string. The recording holds no
+	// user data, and on replay each string is replaced by a made-up one of the same length
+	// that is likely to be unique to its hash. Very short strings may collide.
+	RecordHashed
+)
+
+// Recorder writes the sequence of strings saved to and looked up in an Intern, so that
+// a performance problem seen in production can be reproduced offline with Replay. Attach
+// it with WithRecorder. A Recorder may be shared by several interners.
+type Recorder struct {
+	mu          sync.Mutex
+	w           *bufio.Writer
+	mode        RecordMode
+	sampleEvery uint64
+	err         error
+	buf         []byte
+}
+
+// NewRecorder creates a Recorder writing to w. If sampleEvery is more than 1 only about
+// one in sampleEvery distinct strings is recorded. Strings are chosen by their hash, so
+// every save and lookup of a chosen string is kept, as is the ratio of repeats to new
+// strings.
+func NewRecorder(w io.Writer, mode RecordMode, sampleEvery int) *Recorder {
+	r := &Recorder{
+		w:           bufio.NewWriter(w),
+		mode:        mode,
+		sampleEvery: uint64(max(sampleEvery, 1)),
+	}
+	r.w.WriteString(workloadMagic)
+	r.w.Write([]byte{workloadVersion, byte(mode)})
+	return r
+}
+
+// WithRecorder records every string saved to or looked up in the Intern with r
+func WithRecorder(r *Recorder) Option {
+	return func(i *Intern) {
+		i.recorder = r
+	}
+}
+
+// record writes one operation
+func (r *Recorder) record(op byte, val string) {
+	hash := XXHash64(val, 0)
+	if hash%r.sampleEvery != 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return
+	}
+	b := append(r.buf[:0], op)
+	if r.mode == RecordHashed {
+		b = binary.LittleEndian.AppendUint64(b, hash)
+		b = binary.AppendUvarint(b, uint64(len(val)))
+	} else {
+		b = binary.AppendUvarint(b, uint64(len(val)))
+		b = append(b, val...)
+	}
+	r.buf = b
+	_, r.err = r.w.Write(b)
+}
+
+// Flush writes any buffered records to the underlying writer. It returns the first error
+// met while recording.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.err = r.w.Flush()
+	return r.err
+}
+
+// Workload is a recorded sequence of saves and lookups, read into memory so that it can
+// be replayed against any number of candidate configurations.
+type Workload struct {
+	ops  []byte
+	vals []string
+}
+
+// ReadWorkload reads a recording made by a Recorder
+func ReadWorkload(r io.Reader) (*Workload, error) {
+	br := bufio.NewReader(r)
+	var header [6]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrWorkload, err)
+	}
+	if string(header[:4]) != workloadMagic {
+		return nil, fmt.Errorf("%w: bad magic %q", ErrWorkload, header[:4])
+	}
+	if header[4] != workloadVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrWorkload, header[4])
+	}
+	mode := RecordMode(header[5])
+	if mode != RecordFull && mode != RecordHashed {
+		return nil, fmt.Errorf("%w: unknown mode %d", ErrWorkload, mode)
+	}
+
+	w := &Workload{}
+	var buf []byte
+	var rec bytes.Buffer
+	for {
+		op, err := br.ReadByte()
+		if err == io.EOF {
+			return w, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrWorkload, err)
+		}
+		if op != opSave && op != opLookup {
+			return nil, fmt.Errorf("%w: unknown op %d in record %d", ErrWorkload, op, len(w.ops))
+		}
+		var hash uint64
+		if mode == RecordHashed {
+			var b [8]byte
+			if _, err := io.ReadFull(br, b[:]); err != nil {
+				return nil, fmt.Errorf("%w: record %d is truncated", ErrWorkload, len(w.ops))
+			}
+			hash = binary.LittleEndian.Uint64(b[:])
+		}
+		l, err := binary.ReadUvarint(br)
+		if err != nil || l > 1<<31 {
+			return nil, fmt.Errorf("%w: bad length in record %d", ErrWorkload, len(w.ops))
+		}
+		if mode == RecordHashed {
+			buf = standIn(buf[:0], hash, int(l))
+		} else {
+			// The buffer only grows as the bytes arrive, so a bad length in a short
+			// recording can't make us allocate a huge buffer up front
+			rec.Reset()
+			if _, err := io.CopyN(&rec, br, int64(l)); err != nil {
+				return nil, fmt.Errorf("%w: record %d is truncated", ErrWorkload, len(w.ops))
+			}
+			buf = rec.Bytes()
+		}
+		w.ops = append(w.ops, op)
+		w.vals = append(w.vals, string(buf))
+	}
+}
+
+// standIn makes up a string of length l to replace one with the given hash
+func standIn(b []byte, hash uint64, l int) []byte {
+	for len(b) < l {
+		b = binary.LittleEndian.AppendUint64(b, hash)
+		hash = hash*xxPrime1 + xxPrime2
+	}
+	return b[:l]
+}
+
+// Len returns the number of operations in the workload
+func (w *Workload) Len() int {
+	return len(w.ops)
+}
+
+// ReplayResult describes a replay of a Workload
+type ReplayResult struct {
+	// Saves and Lookups count the operations replayed
+	Saves   int
+	Lookups int
+	// Hits counts the lookups that found their string
+	Hits int
+	// Failed counts the saves that were turned away, for instance by WithMaxBytes
+	Failed int
+	// Elapsed is how long the operations took
+	Elapsed time.Duration
+}
+
+// Replay repeats the workload's saves and lookups against in, in the order they were
+// recorded. Compare candidate table designs by replaying the same workload against
+// interners created with different options, and use in.Stats and in.MemoryUsage
+// alongside the result.
+func (w *Workload) Replay(in *Intern) ReplayResult {
+	var r ReplayResult
+	start := time.Now()
+	for j, op := range w.ops {
+		if op == opSave {
+			r.Saves++
+			if _, err := in.TrySave(w.vals[j]); err != nil {
+				r.Failed++
+			}
+			continue
+		}
+		r.Lookups++
+		if _, ok := in.Lookup(w.vals[j]); ok {
+			r.Hits++
+		}
+	}
+	r.Elapsed = time.Since(start)
+	return r
+}
@@ -0,0 +1,101 @@
+package intern_test
+
+import (
+	"bytes"
+	"errors"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordWorkload saves 100 distinct strings three times each, and looks up a few
+func recordWorkload(t *testing.T, mode intern.RecordMode, sampleEvery int) *intern.Workload {
+	var buf bytes.Buffer
+	rec := intern.NewRecorder(&buf, mode, sampleEvery)
+	in := intern.New(16, intern.WithRecorder(rec))
+	for range 3 {
+		for j := range 100 {
+			in.Save("value-" + strconv.Itoa(j))
+		}
+	}
+	in.Lookup("value-1")
+	in.Lookup("missing")
+	assert.NoError(t, rec.Flush())
+
+	w, err := intern.ReadWorkload(&buf)
+	assert.NoError(t, err)
+	return w
+}
+
+func TestWorkloadReplay(t *testing.T) {
+	for _, mode := range []intern.RecordMode{intern.RecordFull, intern.RecordHashed} {
+		w := recordWorkload(t, mode, 1)
+		assert.Equal(t, 302, w.Len())
+
+		for _, opts := range [][]intern.Option{nil, {intern.WithCuckoo()}} {
+			in := intern.New(16, opts...)
+			r := w.Replay(in)
+			assert.Equal(t, 300, r.Saves)
+			assert.Equal(t, 2, r.Lookups)
+			assert.Equal(t, 1, r.Hits)
+			assert.Zero(t, r.Failed)
+			assert.Equal(t, 100, in.Len())
+		}
+	}
+}
+
+func TestWorkloadReplayFull(t *testing.T) {
+	w := recordWorkload(t, intern.RecordFull, 1)
+	in := intern.New(16)
+	w.Replay(in)
+	_, ok := in.Lookup("value-42")
+	assert.True(t, ok)
+}
+
+func TestWorkloadSampled(t *testing.T) {
+	w := recordWorkload(t, intern.RecordHashed, 4)
+	in := intern.New(16)
+	r := w.Replay(in)
+	// Each chosen string keeps all three of its saves
+	assert.Equal(t, 3*in.Len(), r.Saves)
+	assert.True(t, in.Len() > 5 && in.Len() < 50, in.Len())
+}
+
+func TestReadWorkloadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  string
+	}{
+		{name: "empty", data: "", err: "intern: invalid workload recording: EOF"},
+		{name: "magic", data: "ISTR\x01\x00", err: `intern: invalid workload recording: bad magic "ISTR"`},
+		{name: "version", data: "IREC\x02\x00", err: "intern: invalid workload recording: unsupported version 2"},
+		{name: "mode", data: "IREC\x01\x07", err: "intern: invalid workload recording: unknown mode 7"},
+		{name: "op", data: "IREC\x01\x00\x09", err: "intern: invalid workload recording: unknown op 9 in record 0"},
+		{name: "truncated", data: "IREC\x01\x00\x01\x05ab", err: "intern: invalid workload recording: record 0 is truncated"},
+		{name: "huge length", data: "IREC\x01\x00\x01\x80\x80\x80\x80\x08ab", err: "intern: invalid workload recording: record 0 is truncated"},
+		{name: "bad length", data: "IREC\x01\x00\x01\x81\x80\x80\x80\x08ab", err: "intern: invalid workload recording: bad length in record 0"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := intern.ReadWorkload(bytes.NewReader([]byte(test.data)))
+			assert.True(t, errors.Is(err, intern.ErrWorkload))
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestReadWorkloadHugeLength(t *testing.T) {
+	// A record claiming to be 2GB long in a recording of a few bytes mustn't make
+	// ReadWorkload allocate the 2GB
+	data := []byte("IREC\x01\x00\x01\x80\x80\x80\x80\x08ab")
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	_, err := intern.ReadWorkload(bytes.NewReader(data))
+	runtime.ReadMemStats(&after)
+	assert.True(t, errors.Is(err, intern.ErrWorkload))
+	assert.True(t, after.TotalAlloc-before.TotalAlloc < 1<<20, "allocated %d bytes", after.TotalAlloc-before.TotalAlloc)
+}
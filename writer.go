@@ -0,0 +1,69 @@
+package intern
+
+import (
+	"bytes"
+	"unsafe"
+)
+
+// LineWriter is an io.Writer that interns each newline-delimited line written to it. As
+// with bufio.ScanLines the newline and any carriage return before it are dropped.
+type LineWriter struct {
+	in      *Intern
+	partial []byte
+}
+
+// NewLineWriter creates a LineWriter that saves lines into in.
+func NewLineWriter(in *Intern) *LineWriter {
+	return &LineWriter{in: in}
+}
+
+// Write interns every complete line in p. Any trailing incomplete line is buffered until
+// the rest of it is written, or until Close is called.
+func (w *LineWriter) Write(p []byte) (int, error) {
+	var n int
+	for {
+		end := bytes.IndexByte(p, '\n')
+		if end < 0 {
+			break
+		}
+		line := p[:end]
+		partial := len(w.partial)
+		if partial != 0 {
+			w.partial = append(w.partial, line...)
+			line = w.partial
+		}
+		if _, err := w.in.saveBytes(dropCR(line)); err != nil {
+			w.partial = w.partial[:partial]
+			return n, err
+		}
+		w.partial = w.partial[:0]
+		p = p[end+1:]
+		n += end + 1
+	}
+	w.partial = append(w.partial, p...)
+	return n + len(p), nil
+}
+
+// Close interns any final line that was not terminated by a newline.
+func (w *LineWriter) Close() error {
+	if len(w.partial) != 0 {
+		if _, err := w.in.saveBytes(dropCR(w.partial)); err != nil {
+			return err
+		}
+		w.partial = w.partial[:0]
+	}
+	return nil
+}
+
+func dropCR(line []byte) []byte {
+	if len(line) > 0 && line[len(line)-1] == '\r' {
+		return line[:len(line)-1]
+	}
+	return line
+}
+
+// saveBytes saves the string held in b. The arena takes a copy, so there is no need
+// to convert b to a string first.
+func (i *Intern) saveBytes(b []byte) (int, error) {
+	return i.TrySave(unsafe.String(unsafe.SliceData(b), len(b)))
+}
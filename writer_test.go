@@ -0,0 +1,44 @@
+package intern_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineWriter(t *testing.T) {
+	in := intern.New(16)
+	w := intern.NewLineWriter(in)
+
+	_, err := io.WriteString(w, "GET /a\nGET /b\r\nGET")
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, " /a\nPOST /c")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, in.Len())
+
+	assert.NoError(t, w.Close())
+
+	var lines []string
+	for _, val := range in.AllInOrder() {
+		lines = append(lines, val)
+	}
+	assert.Equal(t, []string{"GET /a", "GET /b", "POST /c"}, lines)
+}
+
+func TestLineWriterCopy(t *testing.T) {
+	var text strings.Builder
+	for j := 0; j < 10000; j++ {
+		fmt.Fprintf(&text, "line %d\n", j%100)
+	}
+
+	in := intern.New(16)
+	w := intern.NewLineWriter(in)
+	_, err := io.Copy(w, strings.NewReader(text.String()))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	assert.Equal(t, 100, in.Len())
+}
@@ -0,0 +1,68 @@
+package intern
+
+import "math/bits"
+
+// wyhash's default secret, and the constant it mixes with the length
+const (
+	wyp0 uint64 = 0xa0761d6478bd642f
+	wyp1 uint64 = 0xe7037ed1a0b428db
+	wyp2 uint64 = 0x8ebc6af09c88c6e3
+	wyp3 uint64 = 0x589965cc75374cc3
+	wyp4 uint64 = 0x1d8e4e27c47d124f
+)
+
+// Wyhash is a Hasher based on wyhash. It is the same variant of wyhash that the Go
+// runtime uses on platforms without AES instructions, but with a fixed secret rather than
+// a random one, so results don't change from process to process. It is very fast for
+// short strings.
+func Wyhash(val string, seed uint64) uint64 {
+	var a, b uint64
+	seed ^= wyp0
+	n := len(val)
+	switch {
+	case n == 0:
+		return seed
+	case n < 4:
+		a = uint64(val[0]) | uint64(val[n>>1])<<8 | uint64(val[n-1])<<16
+	case n == 4:
+		a = uint64(read32(val))
+		b = a
+	case n < 8:
+		a = uint64(read32(val))
+		b = uint64(read32(val[n-4:]))
+	case n == 8:
+		a = read64(val)
+		b = a
+	case n <= 16:
+		a = read64(val)
+		b = read64(val[n-8:])
+	default:
+		// p is the start of the unhashed data. The final reads may overlap data already
+		// hashed, so we keep the whole string around
+		p, l := 0, n
+		if l > 48 {
+			seed1 := seed
+			seed2 := seed
+			for ; l > 48; l -= 48 {
+				seed = wymix(read64(val[p:])^wyp1, read64(val[p+8:])^seed)
+				seed1 = wymix(read64(val[p+16:])^wyp2, read64(val[p+24:])^seed1)
+				seed2 = wymix(read64(val[p+32:])^wyp3, read64(val[p+40:])^seed2)
+				p += 48
+			}
+			seed ^= seed1 ^ seed2
+		}
+		for ; l > 16; l -= 16 {
+			seed = wymix(read64(val[p:])^wyp1, read64(val[p+8:])^seed)
+			p += 16
+		}
+		a = read64(val[p+l-16:])
+		b = read64(val[p+l-8:])
+	}
+
+	return wymix(wyp4^uint64(n), wymix(a^wyp1, b^seed))
+}
+
+func wymix(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return hi ^ lo
+}
@@ -0,0 +1,84 @@
+package intern
+
+import "math/bits"
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// XXHash64 is a Hasher implementing the 64-bit xxHash algorithm (XXH64). Its results
+// match other XXH64 implementations, and don't change from process to process.
+func XXHash64(val string, seed uint64) uint64 {
+	n := len(val)
+	var h uint64
+	if n >= 32 {
+		v1 := seed + xxPrime1 + xxPrime2
+		v2 := seed + xxPrime2
+		v3 := seed
+		v4 := seed - xxPrime1
+		for ; len(val) >= 32; val = val[32:] {
+			v1 = xxRound(v1, read64(val[0:8]))
+			v2 = xxRound(v2, read64(val[8:16]))
+			v3 = xxRound(v3, read64(val[16:24]))
+			v4 = xxRound(v4, read64(val[24:32]))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMergeRound(h, v1)
+		h = xxMergeRound(h, v2)
+		h = xxMergeRound(h, v3)
+		h = xxMergeRound(h, v4)
+	} else {
+		h = seed + xxPrime5
+	}
+	h += uint64(n)
+
+	for ; len(val) >= 8; val = val[8:] {
+		h ^= xxRound(0, read64(val[:8]))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if len(val) >= 4 {
+		h ^= uint64(read32(val[:4])) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		val = val[4:]
+	}
+	for j := 0; j < len(val); j++ {
+		h ^= uint64(val[j]) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMergeRound(acc, val uint64) uint64 {
+	acc ^= xxRound(0, val)
+	return acc*xxPrime1 + xxPrime4
+}
+
+// read64 reads a little-endian uint64 from the start of s. The compiler turns this into
+// a single load where the platform allows
+func read64(s string) uint64 {
+	_ = s[7]
+	return uint64(s[0]) | uint64(s[1])<<8 | uint64(s[2])<<16 | uint64(s[3])<<24 |
+		uint64(s[4])<<32 | uint64(s[5])<<40 | uint64(s[6])<<48 | uint64(s[7])<<56
+}
+
+// read32 reads a little-endian uint32 from the start of s
+func read32(s string) uint32 {
+	_ = s[3]
+	return uint32(s[0]) | uint32(s[1])<<8 | uint32(s[2])<<16 | uint32(s[3])<<24
+}
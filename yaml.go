@@ -0,0 +1,89 @@
+package intern
+
+import "reflect"
+
+// String is a string that interns itself in the package-level interner used by
+// Deduplicate when it is decoded. Use it for the fields of configuration structs, such
+// as Kubernetes manifests, whose keys and enum values repeat across many documents.
+// It implements encoding.TextUnmarshaler, which gopkg.in/yaml.v2 and v3,
+// sigs.k8s.io/yaml, encoding/json and most TOML libraries use for strings, so it works
+// with them without this package depending on any of them. To intern in an Intern of
+// your own instead, decode into plain strings with Intern.Unmarshal.
+type String string
+
+// UnmarshalText interns text and stores it in s
+func (s *String) UnmarshalText(text []byte) error {
+	*s = String(DeduplicateBytes(text))
+	return nil
+}
+
+// MarshalText returns s as it is, so that a String encodes just like a string
+func (s String) MarshalText() ([]byte, error) {
+	return []byte(s), nil
+}
+
+// Unmarshal decodes data into v with unmarshal, which may be yaml.Unmarshal from
+// gopkg.in/yaml.v3, sigs.k8s.io/yaml, json.Unmarshal or any function like them, and then
+// interns in i every string held in v. Strings are found in exported struct fields,
+// pointers, slices, arrays, interfaces, and the keys and values of maps. Use it with plain
+// string fields: String fields are interned in the default interner as they are
+// decoded, and then in i as well.
+func (i *Intern) Unmarshal(unmarshal func(data []byte, v any) error, data []byte, v any) error {
+	if err := unmarshal(data, v); err != nil {
+		return err
+	}
+	i.deduplicateReflect(reflect.ValueOf(v), make(map[uintptr]bool))
+	return nil
+}
+
+// deduplicateReflect interns the strings held in v. seen holds the pointers already
+// followed, so that shared or cyclic data is visited once.
+func (i *Intern) deduplicateReflect(v reflect.Value, seen map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(i.Deduplicate(v.String()))
+		}
+	case reflect.Pointer:
+		if v.IsNil() || seen[v.Pointer()] {
+			return
+		}
+		seen[v.Pointer()] = true
+		i.deduplicateReflect(v.Elem(), seen)
+	case reflect.Interface:
+		if v.IsNil() || !v.CanSet() {
+			return
+		}
+		// The value held by an interface can't be changed in place, so work on a copy
+		e := reflect.New(v.Elem().Type()).Elem()
+		e.Set(v.Elem())
+		i.deduplicateReflect(e, seen)
+		v.Set(e)
+	case reflect.Struct:
+		t := v.Type()
+		for j := range v.NumField() {
+			if t.Field(j).IsExported() {
+				i.deduplicateReflect(v.Field(j), seen)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for j := range v.Len() {
+			i.deduplicateReflect(v.Index(j), seen)
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		kt, et := v.Type().Key(), v.Type().Elem()
+		for _, k := range v.MapKeys() {
+			nk := reflect.New(kt).Elem()
+			nk.Set(k)
+			i.deduplicateReflect(nk, seen)
+			e := reflect.New(et).Elem()
+			e.Set(v.MapIndex(k))
+			i.deduplicateReflect(e, seen)
+			// Assigning with an equal key replaces the key stored in the map
+			v.SetMapIndex(nk, e)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package intern_test
+
+import (
+	"encoding/json"
+	"testing"
+	"unsafe"
+
+	"github.com/philpearl/intern"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestString(t *testing.T) {
+	// encoding/json uses UnmarshalText just as the YAML libraries do
+	type container struct {
+		Name  intern.String `json:"name"`
+		Image intern.String `json:"image"`
+	}
+	var cs []container
+	assert.NoError(t, json.Unmarshal([]byte(`[{"name":"web","image":"nginx:1.27"},{"name":"sidecar","image":"nginx:1.27"}]`), &cs))
+	assert.Equal(t, intern.String("nginx:1.27"), cs[0].Image)
+	assert.Equal(t, unsafe.StringData(string(cs[0].Image)), unsafe.StringData(string(cs[1].Image)))
+	assert.Equal(t, intern.Deduplicate("nginx:1.27"), string(cs[1].Image))
+
+	out, err := json.Marshal(cs[0])
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"web","image":"nginx:1.27"}`, string(out))
+
+	// The text is copied, so the decoder may reuse its buffer
+	text := []byte("reused")
+	var s intern.String
+	assert.NoError(t, s.UnmarshalText(text))
+	text[0] = 'X'
+	assert.Equal(t, intern.String("reused"), s)
+}
+
+func TestUnmarshal(t *testing.T) {
+	type container struct {
+		Name   string            `json:"name"`
+		Image  *string           `json:"image"`
+		Args   []string          `json:"args"`
+		Labels map[string]string `json:"labels"`
+		Extra  any               `json:"extra"`
+	}
+	data := []byte(`[
+		{"name":"web","image":"nginx:1.27","args":["-v"],"labels":{"app":"web"},"extra":{"tier":"front"}},
+		{"name":"sidecar","image":"nginx:1.27","args":["-v"],"labels":{"app":"web"},"extra":["front"]}
+	]`)
+	in := intern.New(16)
+	var cs []container
+	assert.NoError(t, in.Unmarshal(json.Unmarshal, data, &cs))
+	assert.Len(t, cs, 2)
+
+	// Every string now comes from in
+	same := func(val string) {
+		t.Helper()
+		_, ok := in.Lookup(val)
+		assert.True(t, ok, val)
+		assert.Equal(t, unsafe.StringData(in.Deduplicate(val)), unsafe.StringData(val))
+	}
+	for _, c := range cs {
+		same(c.Name)
+		same(*c.Image)
+		same(c.Args[0])
+		for k, v := range c.Labels {
+			same(k)
+			same(v)
+		}
+	}
+	for k, v := range cs[0].Extra.(map[string]any) {
+		same(k)
+		same(v.(string))
+	}
+	same(cs[1].Extra.([]any)[0].(string))
+	assert.Equal(t, 7, in.Len())
+
+	var bad []container
+	assert.Error(t, in.Unmarshal(json.Unmarshal, []byte("{"), &bad))
+}